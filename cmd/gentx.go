@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/0glabs/evmchainbench/cmd/option"
 	"github.com/0glabs/evmchainbench/lib/cmd/gentx"
@@ -14,14 +15,20 @@ var gentxCmd = &cobra.Command{
 	Long:  "To generate transactions and store them onto disk",
 	Run: func(cmd *cobra.Command, args []string) {
 
-		httpRpc, _ := cmd.Flags().GetString("http-rpc")
+		httpRpc := option.HttpRpc(cmd)
 		faucetPrivateKey, _ := cmd.Flags().GetString("faucet-private-key")
 		senderCount, _ := cmd.Flags().GetInt("sender-count")
 		txCount, _ := cmd.Flags().GetInt("tx-count")
 		txType, _ := cmd.Flags().GetString("tx-type")
 		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+		compression, _ := cmd.Flags().GetString("compression")
+		streamBuffer, err := option.StreamBuffer(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		generatorOptions := option.GeneratorOptions(cmd)
 
-		gentx.GenTx(httpRpc, faucetPrivateKey, senderCount, txCount, txType, txStoreDir)
+		gentx.GenTx(httpRpc, faucetPrivateKey, senderCount, txCount, txType, txStoreDir, compression, streamBuffer, generatorOptions)
 		fmt.Println("gentx called")
 	},
 }
@@ -30,4 +37,6 @@ func init() {
 	rootCmd.AddCommand(gentxCmd)
 	option.OptionsForGeneration(gentxCmd)
 	option.OptionsForTxStore(gentxCmd)
+	option.OptionsForCompression(gentxCmd)
+	option.OptionsForStreamBuffer(gentxCmd)
 }
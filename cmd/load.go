@@ -13,10 +13,17 @@ var loadCmd = &cobra.Command{
 	Short: "Load previously generated transactions and run the benchmark",
 	Long:  "Load previously generated transactions and run the benchmark",
 	Run: func(cmd *cobra.Command, args []string) {
-		httpRpc, _ := cmd.Flags().GetString("http-rpc")
+		httpRpc := option.HttpRpc(cmd)
+		wsRpc, _ := cmd.Flags().GetString("ws-rpc")
 		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
-		loader := load.NewLoader(httpRpc, txStoreDir)
-		err := loader.LoadAndRun()
+		resume, _ := cmd.Flags().GetBool("resume")
+		runOptions, err := option.RunOptions(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		loader := load.NewLoader(httpRpc, wsRpc, txStoreDir, resume, runOptions)
+		_, err = loader.LoadAndRun()
 		if err != nil {
 			log.Fatalf("Failed to load and run: %v", err)
 		}
@@ -26,4 +33,10 @@ var loadCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(loadCmd)
 	option.OptionsForTxStore(loadCmd)
+	option.OptionsForMeasurement(loadCmd)
+	option.OptionsForTransmission(loadCmd)
+	option.OptionsForPacing(loadCmd)
+	option.OptionsForStreamBuffer(loadCmd)
+	loadCmd.Flags().BoolP("fill-nonce-gaps", "", false, "Send the transactions of senders stuck behind a dropped transaction again")
+	loadCmd.Flags().BoolP("resume", "", false, "Continue an interrupted load from its last checkpoint and the pending nonce of every sender")
 }
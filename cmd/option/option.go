@@ -1,14 +1,174 @@
 package option
 
 import (
+	"fmt"
+	"math/big"
+
 	"github.com/spf13/cobra"
+
+	"github.com/0glabs/evmchainbench/lib/cmd/run"
+	"github.com/0glabs/evmchainbench/lib/generator"
 )
 
+// DefaultFaucetPrivateKey is the key of the faucet account of local devnets.
+const DefaultFaucetPrivateKey = "0xfffdbb37105441e14b0ee6330d855d8504ff39e705c3afa8f859ac9865f99306"
+
 func OptionsForGeneration(cmd *cobra.Command) {
-	cmd.Flags().StringP("faucet-private-key", "f", "0xfffdbb37105441e14b0ee6330d855d8504ff39e705c3afa8f859ac9865f99306", "Private key of a faucet account")
+	cmd.Flags().StringP("faucet-private-key", "f", DefaultFaucetPrivateKey, "Private key of a faucet account")
 	cmd.Flags().IntP("sender-count", "s", 4, "The number of senders of generated transactions")
 	cmd.Flags().IntP("tx-count", "t", 100000, "The number of tx count each sender will broadcast")
 	cmd.Flags().StringP("tx-type", "p", "simple", "Transaction type: simple, erc20, or uniswap")
+	cmd.Flags().BoolP("fill-nonce-gaps", "", false, "Fill nonce gaps left by dropped transactions during preparation and broadcast")
+	cmd.Flags().StringP("tx-envelope", "", "auto", "Transaction envelope: auto, legacy, access-list, or dynamic-fee")
+	cmd.Flags().Int64P("gas-tip-cap", "", 0, "Gas tip cap in wei of dynamic-fee transactions, 0 to use the node's suggestion")
+	cmd.Flags().Int64P("gas-fee-cap", "", 0, "Gas fee cap in wei of dynamic-fee transactions, 0 to derive it from the base fee")
+	cmd.Flags().Uint64P("fee-history-blocks", "", 20, "The number of recent blocks eth_feeHistory is queried for")
+	cmd.Flags().Float64P("fee-percentile", "", 50, "Reward percentile of recent blocks used as the gas tip cap")
+	cmd.Flags().Float64P("fee-headroom", "", 1, "Multiple of the base fee added to the gas fee cap as headroom")
+	cmd.Flags().Int64P("fee-bump-percent", "", 15, "Percentage by which fees are bumped when a transaction is underpriced")
+	cmd.Flags().IntP("max-fee-bumps", "", 3, "How often an underpriced transaction is re-signed with bumped fees")
+	cmd.Flags().StringP("gas-mode", "", "estimate", "Gas limit of contract calls: fixed, estimate (once per workload), or per-tx")
+	cmd.Flags().Float64P("gas-factor", "", 0, "Multiplier of estimated gas, 0 to use the workload default")
+	cmd.Flags().IntP("gen-workers", "", 0, "The number of workers signing transactions, 0 for one per CPU")
+	cmd.Flags().DurationP("swap-deadline", "", 0, "How long Uniswap swaps stay valid, 0 for 15m or 10 years for stored transactions")
+}
+
+func OptionsForMeasurement(cmd *cobra.Command) {
+	cmd.Flags().IntP("gas-report-samples", "", 100, "The number of receipts compared with their gas limit after the run, 0 to disable")
+}
+
+func OptionsForPacing(cmd *cobra.Command) {
+	cmd.Flags().Float64P("target-tps", "", 0, "Send transactions open-loop at this rate regardless of inclusion, 0 to keep the mempool filled instead")
+	cmd.Flags().StringP("load-profile", "", "", "A JSON file of phases (ramp, step, spike, sine) to send transactions open-loop by")
+}
+
+func OptionsForTransmission(cmd *cobra.Command) {
+	cmd.Flags().IntP("batch-size", "", 1, "The number of transactions per sender sent in one JSON-RPC batch, 1 to send them one by one")
+	cmd.Flags().StringP("rpc-strategy", "", run.RoundRobinStrategy, "How transactions are distributed over the HTTP endpoints: round-robin per transaction, sender (one endpoint per sender), or random")
+	cmd.Flags().IntP("send-workers", "", 0, "The number of transactions in flight at once, 0 for one per sender")
+	cmd.Flags().IntP("connections", "", 0, "The number of HTTP connections per endpoint, 0 for one per send worker")
+	cmd.Flags().StringToStringP("error-policy", "", nil, "Policies (skip, retry, resync or abort) of error classes (already-known, nonce-too-low, nonce-gap, underpriced, txpool-full, insufficient-funds, transport, other), e.g. txpool-full=abort,nonce-gap=skip")
+	cmd.Flags().IntP("max-retries", "", run.DefaultMaxRetries, "How often a transaction is retried under the retry and resync policies")
+	cmd.Flags().DurationP("retry-backoff", "", run.DefaultRetryBackoff, "The backoff before the first retry, doubled with every further retry")
+}
+
+// HttpRpc returns the first HTTP endpoint, which everything but sending the
+// benchmark transactions goes through.
+func HttpRpc(cmd *cobra.Command) string {
+	httpRpcs, _ := cmd.Flags().GetStringSlice("http-rpc")
+	if len(httpRpcs) == 0 {
+		return ""
+	}
+	return httpRpcs[0]
+}
+
+func OptionsForStreaming(cmd *cobra.Command) {
+	cmd.Flags().BoolP("stream", "", false, "Send transactions while they are generated instead of generating all of them first")
+	OptionsForStreamBuffer(cmd)
+}
+
+// OptionsForStreamBuffer is for commands which always stream, like load
+// reading the store while sending.
+func OptionsForStreamBuffer(cmd *cobra.Command) {
+	cmd.Flags().IntP("stream-buffer", "", run.DefaultStreamBuffer, "The number of transactions per sender produced ahead of sending or storing them when streaming, 0 for the default")
+}
+
+// StreamBuffer returns the --stream-buffer of cmd, the default if it is 0.
+func StreamBuffer(cmd *cobra.Command) (int, error) {
+	streamBuffer, _ := cmd.Flags().GetInt("stream-buffer")
+	if streamBuffer < 0 {
+		return 0, fmt.Errorf("the stream buffer %d is negative", streamBuffer)
+	}
+	if streamBuffer == 0 {
+		return run.DefaultStreamBuffer, nil
+	}
+	return streamBuffer, nil
+}
+
+func RunOptions(cmd *cobra.Command) (run.Options, error) {
+	streamBuffer, err := StreamBuffer(cmd)
+	if err != nil {
+		return run.Options{}, err
+	}
+
+	mempool, _ := cmd.Flags().GetInt("mempool")
+	gasReportSamples, _ := cmd.Flags().GetInt("gas-report-samples")
+	stream, _ := cmd.Flags().GetBool("stream")
+	targetTPS, _ := cmd.Flags().GetFloat64("target-tps")
+	loadProfile, _ := cmd.Flags().GetString("load-profile")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	rpcUrls, _ := cmd.Flags().GetStringSlice("http-rpc")
+	rpcStrategy, _ := cmd.Flags().GetString("rpc-strategy")
+	workers, _ := cmd.Flags().GetInt("send-workers")
+	connections, _ := cmd.Flags().GetInt("connections")
+	errorPolicies, _ := cmd.Flags().GetStringToString("error-policy")
+	maxRetries, _ := cmd.Flags().GetInt("max-retries")
+	retryBackoff, _ := cmd.Flags().GetDuration("retry-backoff")
+	fillNonceGaps, _ := cmd.Flags().GetBool("fill-nonce-gaps")
+
+	return run.Options{
+		Mempool:          mempool,
+		GasReportSamples: gasReportSamples,
+		Stream:           stream,
+		StreamBuffer:     streamBuffer,
+		TargetTPS:        targetTPS,
+		LoadProfile:      loadProfile,
+		BatchSize:        batchSize,
+		RpcUrls:          rpcUrls,
+		RpcStrategy:      rpcStrategy,
+		Workers:          workers,
+		Connections:      connections,
+		ErrorPolicies:    errorPolicies,
+		MaxRetries:       maxRetries,
+		RetryBackoff:     retryBackoff,
+		FillNonceGaps:    fillNonceGaps,
+	}, nil
+}
+
+func GeneratorOptions(cmd *cobra.Command) generator.Options {
+	fillNonceGaps, _ := cmd.Flags().GetBool("fill-nonce-gaps")
+	txEnvelope, _ := cmd.Flags().GetString("tx-envelope")
+	gasTipCap, _ := cmd.Flags().GetInt64("gas-tip-cap")
+	gasFeeCap, _ := cmd.Flags().GetInt64("gas-fee-cap")
+	feeHistoryBlocks, _ := cmd.Flags().GetUint64("fee-history-blocks")
+	feePercentile, _ := cmd.Flags().GetFloat64("fee-percentile")
+	feeHeadroom, _ := cmd.Flags().GetFloat64("fee-headroom")
+	feeBumpPercent, _ := cmd.Flags().GetInt64("fee-bump-percent")
+	maxFeeBumps, _ := cmd.Flags().GetInt("max-fee-bumps")
+	gasMode, _ := cmd.Flags().GetString("gas-mode")
+	gasFactor, _ := cmd.Flags().GetFloat64("gas-factor")
+	genWorkers, _ := cmd.Flags().GetInt("gen-workers")
+	swapDeadline, _ := cmd.Flags().GetDuration("swap-deadline")
+
+	options := generator.Options{
+		FillNonceGaps: fillNonceGaps,
+		FeeStrategy: generator.FeeStrategy{
+			HistoryBlocks: feeHistoryBlocks,
+			Percentile:    feePercentile,
+			Headroom:      feeHeadroom,
+			BumpPercent:   feeBumpPercent,
+			MaxBumps:      maxFeeBumps,
+		},
+		GasMode:      gasMode,
+		GasFactor:    gasFactor,
+		Workers:      genWorkers,
+		SwapDeadline: swapDeadline,
+	}
+	if txEnvelope != "auto" {
+		options.TxType = txEnvelope
+	}
+	if gasTipCap > 0 {
+		options.GasTipCap = big.NewInt(gasTipCap)
+	}
+	if gasFeeCap > 0 {
+		options.GasFeeCap = big.NewInt(gasFeeCap)
+	}
+
+	return options
+}
+
+func OptionsForCompression(cmd *cobra.Command) {
+	cmd.Flags().StringP("compression", "", "none", "Compression of stored transaction files: none, gzip, or zstd")
 }
 
 func OptionsForTxStore(cmd *cobra.Command) {
@@ -31,7 +31,7 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringP("http-rpc", "", "http://127.0.0.1:8545", "RPC HTTP Endpoint")
+	rootCmd.PersistentFlags().StringSliceP("http-rpc", "", []string{"http://127.0.0.1:8545"}, "RPC HTTP Endpoint, or a comma-separated list of endpoints transactions are distributed over")
 	rootCmd.PersistentFlags().StringP("ws-rpc", "", "ws://127.0.0.1:8546", "RPC WS Endpoint")
 	rootCmd.PersistentFlags().IntP("mempool", "", 5000, "Mempool size")
 }
@@ -4,6 +4,8 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"log"
+
 	"github.com/0glabs/evmchainbench/cmd/option"
 	"github.com/0glabs/evmchainbench/lib/cmd/run"
 	"github.com/spf13/cobra"
@@ -15,19 +17,27 @@ var runCmd = &cobra.Command{
 	Short: "To run the benchmark",
 	Long:  "To run the benchmark",
 	Run: func(cmd *cobra.Command, args []string) {
-		httpRpc, _ := cmd.Flags().GetString("http-rpc")
+		httpRpc := option.HttpRpc(cmd)
 		wsRpc, _ := cmd.Flags().GetString("ws-rpc")
 		faucetPrivateKey, _ := cmd.Flags().GetString("faucet-private-key")
 		senderCount, _ := cmd.Flags().GetInt("sender-count")
 		txCount, _ := cmd.Flags().GetInt("tx-count")
 		txType, _ := cmd.Flags().GetString("tx-type")
-		mempool, _ := cmd.Flags().GetInt("mempool")
+		runOptions, err := option.RunOptions(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		generatorOptions := option.GeneratorOptions(cmd)
 
-		run.Run(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, mempool)
+		run.Run(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, runOptions, generatorOptions)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
 	option.OptionsForGeneration(runCmd)
+	option.OptionsForMeasurement(runCmd)
+	option.OptionsForTransmission(runCmd)
+	option.OptionsForStreaming(runCmd)
+	option.OptionsForPacing(runCmd)
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"log"
+	"time"
+
+	"github.com/0glabs/evmchainbench/cmd/option"
+	"github.com/0glabs/evmchainbench/lib/cmd/search"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Search the maximum sustainable TPS",
+	Long:  "Binary-search the highest open-loop rate at which inclusion keeps up and the p95 inclusion latency stays within the SLO",
+	Run: func(cmd *cobra.Command, args []string) {
+		httpRpc := option.HttpRpc(cmd)
+		wsRpc, _ := cmd.Flags().GetString("ws-rpc")
+		faucetPrivateKey, _ := cmd.Flags().GetString("faucet-private-key")
+		senderCount, _ := cmd.Flags().GetInt("sender-count")
+		txType, _ := cmd.Flags().GetString("tx-type")
+		minTPS, _ := cmd.Flags().GetFloat64("min-tps")
+		maxTPS, _ := cmd.Flags().GetFloat64("max-tps")
+		precision, _ := cmd.Flags().GetFloat64("precision")
+		window, _ := cmd.Flags().GetDuration("window")
+		latencySLO, _ := cmd.Flags().GetDuration("p95-slo")
+		minInclusion, _ := cmd.Flags().GetFloat64("min-inclusion")
+		runOptions, err := option.RunOptions(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		generatorOptions := option.GeneratorOptions(cmd)
+
+		searchOptions := search.Options{
+			MinTPS:       minTPS,
+			MaxTPS:       maxTPS,
+			Precision:    precision,
+			Window:       window,
+			LatencySLO:   latencySLO,
+			MinInclusion: minInclusion,
+		}
+		_, err = search.Search(httpRpc, wsRpc, faucetPrivateKey, senderCount, txType, searchOptions, runOptions, generatorOptions)
+		if err != nil {
+			log.Fatalf("Failed to search: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	option.OptionsForGeneration(searchCmd)
+	option.OptionsForMeasurement(searchCmd)
+	option.OptionsForTransmission(searchCmd)
+	option.OptionsForStreaming(searchCmd)
+	searchCmd.Flags().Float64P("min-tps", "", 100, "The lowest rate searched")
+	searchCmd.Flags().Float64P("max-tps", "", 20000, "The highest rate searched")
+	searchCmd.Flags().Float64P("precision", "", 100, "The search stops once the range of rates is this narrow")
+	searchCmd.Flags().DurationP("window", "", time.Minute, "How long every rate is offered")
+	searchCmd.Flags().DurationP("p95-slo", "", 5*time.Second, "The highest p95 inclusion latency of a sustainable rate")
+	searchCmd.Flags().Float64P("min-inclusion", "", 0.9, "The lowest ratio of included to offered rate of a sustainable rate")
+}
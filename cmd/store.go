@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/0glabs/evmchainbench/cmd/option"
+	"github.com/0glabs/evmchainbench/lib/cmd/retarget"
+	"github.com/0glabs/evmchainbench/lib/store"
+	"github.com/spf13/cobra"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Inspect, verify, clear, export, import or retarget the stored transactions",
+	Long:  "Inspect, verify, clear, export, import or retarget the transactions stored by gentx",
+}
+
+var storeInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show what the stored transactions contain",
+	Long:  "Show the tx count, senders, nonce range, tx types and total gas of every stored transaction file",
+	Run: func(cmd *cobra.Command, args []string) {
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+
+		inspection, err := store.NewStore(txStoreDir).Inspect()
+		if err != nil {
+			log.Fatalf("Failed to inspect store: %v", err)
+		}
+		fmt.Println(inspection)
+	},
+}
+
+var storeVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check signatures, nonces and chain ID of the stored transactions",
+	Long:  "Check that the stored transactions are validly signed for the chain ID of the manifest and that every sender has consecutive nonces",
+	Run: func(cmd *cobra.Command, args []string) {
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+
+		problems, err := store.NewStore(txStoreDir).Verify()
+		if err != nil {
+			log.Fatalf("Failed to verify store: %v", err)
+		}
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("Store is invalid: %d problems found", len(problems))
+		}
+		fmt.Println("Store is valid")
+	},
+}
+
+var storeClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the stored transactions",
+	Long:  "Remove the stored transactions and their manifest, other files in the directory are kept",
+	Run: func(cmd *cobra.Command, args []string) {
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+
+		removed, err := store.NewStore(txStoreDir).Clear()
+		if err != nil {
+			log.Fatalf("Failed to clear store: %v", err)
+		}
+		fmt.Printf("Removed %d files from %s\n", removed, txStoreDir)
+	},
+}
+
+var storeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the stored transactions as JSONL",
+	Long:  "Export the stored transactions as JSONL, one line per transaction with sender index, nonce, hash and raw signed transaction hex. Prepare transactions have sender index -1. The manifest, if any, comes first in a header line",
+	Run: func(cmd *cobra.Command, args []string) {
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+		file, _ := cmd.Flags().GetString("file")
+
+		var w io.Writer = os.Stdout
+		if file != "-" {
+			f, err := os.Create(file)
+			if err != nil {
+				log.Fatalf("Failed to create %s: %v", file, err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		count, err := store.NewStore(txStoreDir).Export(w)
+		if err != nil {
+			log.Fatalf("Failed to export store: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d transactions\n", count)
+	},
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Replace the stored transactions with transactions from JSONL",
+	Long:  "Replace the stored transactions with transactions from JSONL in the format written by export. The store is only replaced once all transactions are valid, and keeps its manifest unless the JSONL has one",
+	Run: func(cmd *cobra.Command, args []string) {
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+		file, _ := cmd.Flags().GetString("file")
+		compression, _ := cmd.Flags().GetString("compression")
+
+		err := store.ValidateCompression(compression)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				log.Fatalf("Failed to open %s: %v", file, err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		s := store.NewStore(txStoreDir)
+		s.Compression = compression
+		count, err := s.Import(r)
+		if err != nil {
+			log.Fatalf("Failed to import store: %v", err)
+		}
+		fmt.Printf("Imported %d transactions: %v\n", count, s.Written())
+	},
+}
+
+var storeRetargetCmd = &cobra.Command{
+	Use:   "retarget",
+	Short: "Re-sign the stored transactions for another chain",
+	Long:  "Re-sign the stored transactions for the chain at --http-rpc, starting at the pending nonces of the faucet and senders there, and remap the addresses of contracts deployed by the prepare transactions",
+	Run: func(cmd *cobra.Command, args []string) {
+		httpRpc := option.HttpRpc(cmd)
+		faucetPrivateKey, _ := cmd.Flags().GetString("faucet-private-key")
+		txStoreDir, _ := cmd.Flags().GetString("tx-store-dir")
+		compression, _ := cmd.Flags().GetString("compression")
+		if !cmd.Flags().Changed("compression") {
+			// the store keeps its compression unless told otherwise
+			compression = ""
+		}
+
+		err := store.ValidateCompression(compression)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = retarget.Retarget(httpRpc, faucetPrivateKey, txStoreDir, compression)
+		if err != nil {
+			log.Fatalf("Failed to retarget store: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(storeCmd)
+	for _, cmd := range []*cobra.Command{storeInspectCmd, storeVerifyCmd, storeClearCmd} {
+		storeCmd.AddCommand(cmd)
+		option.OptionsForTxStore(cmd)
+	}
+
+	storeCmd.AddCommand(storeExportCmd)
+	option.OptionsForTxStore(storeExportCmd)
+	storeExportCmd.Flags().StringP("file", "", "-", "The JSONL file to write, - for stdout")
+
+	storeCmd.AddCommand(storeImportCmd)
+	option.OptionsForTxStore(storeImportCmd)
+	storeImportCmd.Flags().StringP("file", "", "-", "The JSONL file to read, - for stdin")
+	option.OptionsForCompression(storeImportCmd)
+
+	storeCmd.AddCommand(storeRetargetCmd)
+	option.OptionsForTxStore(storeRetargetCmd)
+	option.OptionsForCompression(storeRetargetCmd)
+	compression := storeRetargetCmd.Flags().Lookup("compression")
+	compression.Usage = "Compression of the retargeted transaction files: none, gzip, or zstd, unchanged if not given"
+	compression.DefValue = ""
+	storeRetargetCmd.Flags().StringP("faucet-private-key", "f", option.DefaultFaucetPrivateKey, "Private key of the faucet account signing the prepare transactions")
+}
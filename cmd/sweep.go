@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/0glabs/evmchainbench/cmd/option"
+	"github.com/0glabs/evmchainbench/lib/cmd/sweep"
+	"github.com/spf13/cobra"
+)
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Run the benchmark with several gas limit multipliers",
+	Long:  "Run the same workload with several gas limit multipliers and compare TPS and gas used per multiplier",
+	Run: func(cmd *cobra.Command, args []string) {
+		httpRpc := option.HttpRpc(cmd)
+		wsRpc, _ := cmd.Flags().GetString("ws-rpc")
+		faucetPrivateKey, _ := cmd.Flags().GetString("faucet-private-key")
+		senderCount, _ := cmd.Flags().GetInt("sender-count")
+		txCount, _ := cmd.Flags().GetInt("tx-count")
+		txType, _ := cmd.Flags().GetString("tx-type")
+		multipliers, _ := cmd.Flags().GetFloat64Slice("gas-multipliers")
+		runOptions, err := option.RunOptions(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		generatorOptions := option.GeneratorOptions(cmd)
+
+		err = sweep.Sweep(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, multipliers, runOptions, generatorOptions)
+		if err != nil {
+			log.Fatalf("Failed to sweep: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+	option.OptionsForGeneration(sweepCmd)
+	option.OptionsForMeasurement(sweepCmd)
+	option.OptionsForTransmission(sweepCmd)
+	option.OptionsForStreaming(sweepCmd)
+	option.OptionsForPacing(sweepCmd)
+	// simple transfers have a fixed gas limit, there is nothing to sweep
+	txType := sweepCmd.Flags().Lookup("tx-type")
+	txType.Value.Set("erc20")
+	txType.DefValue = "erc20"
+	sweepCmd.Flags().Float64SliceP("gas-multipliers", "", []float64{1.0, 1.05, 1.2, 1.5}, "Gas limit multipliers applied to the estimated gas")
+}
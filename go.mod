@@ -5,6 +5,7 @@ go 1.22.5
 require (
 	github.com/ethereum/go-ethereum v1.14.11
 	github.com/gorilla/websocket v1.4.2
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.8.1
 	golang.org/x/term v0.19.0
 )
@@ -28,7 +29,6 @@ require (
 	github.com/google/uuid v1.4.0 // indirect
 	github.com/holiman/uint256 v1.3.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/klauspost/compress v1.17.2 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
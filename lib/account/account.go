@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -13,6 +14,7 @@ import (
 )
 
 type Account struct {
+	mutex      sync.Mutex
 	Nonce      uint64
 	Address    common.Address
 	PrivateKey *ecdsa.PrivateKey
@@ -55,6 +57,9 @@ func CreateFaucetAccount(client *ethclient.Client, privateKey string) (*Account,
 }
 
 func (account *Account) GetNonce() uint64 {
+	account.mutex.Lock()
+	defer account.mutex.Unlock()
+
 	now := account.Nonce
 	account.Nonce += 1
 	return now
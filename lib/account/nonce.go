@@ -0,0 +1,82 @@
+package account
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// PeekNonce returns the nonce the next call of GetNonce will hand out.
+func (account *Account) PeekNonce() uint64 {
+	account.mutex.Lock()
+	defer account.mutex.Unlock()
+
+	return account.Nonce
+}
+
+// ResyncNonce resets the local nonce to the pending nonce of the chain. It is
+// used to recover after a node rejects a tx because of its nonce.
+func (account *Account) ResyncNonce(client *ethclient.Client) (uint64, error) {
+	nonce, err := client.PendingNonceAt(context.Background(), account.Address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce of %s: %w", account.Address.Hex(), err)
+	}
+
+	account.mutex.Lock()
+	account.Nonce = nonce
+	account.mutex.Unlock()
+
+	return nonce, nil
+}
+
+// NonceGap returns the range [from, to) of nonces that were handed out
+// locally but are not yet executable on chain. A non-empty range is only a
+// real gap once the txs of the account have had time to be included,
+// otherwise it simply covers the txs in flight.
+func (account *Account) NonceGap(client *ethclient.Client) (uint64, uint64, error) {
+	pending, err := client.PendingNonceAt(context.Background(), account.Address)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get pending nonce of %s: %w", account.Address.Hex(), err)
+	}
+
+	local := account.PeekNonce()
+	if pending >= local {
+		return local, local, nil
+	}
+
+	return pending, local, nil
+}
+
+// FillNonceGap sends a filler tx built by build for every nonce in [from, to)
+// so that txs queued behind the gap become executable again.
+func (account *Account) FillNonceGap(client *ethclient.Client, from, to uint64, build func(nonce uint64) (*types.Transaction, error)) (types.Transactions, error) {
+	txs := types.Transactions{}
+	for nonce := from; nonce < to; nonce++ {
+		tx, err := build(nonce)
+		if err != nil {
+			return txs, err
+		}
+
+		err = client.SendTransaction(context.Background(), tx)
+		if err != nil {
+			return txs, fmt.Errorf("failed to fill nonce %d of %s: %w", nonce, account.Address.Hex(), err)
+		}
+
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// ReserveNonces hands out count consecutive nonces at once and returns the
+// first of them, so that txs can be signed out of order.
+func (account *Account) ReserveNonces(count uint64) uint64 {
+	account.mutex.Lock()
+	defer account.mutex.Unlock()
+
+	first := account.Nonce
+	account.Nonce += count
+	return first
+}
@@ -1,28 +1,61 @@
 package gentx
 
 import (
+	"fmt"
 	"log"
+	"sync"
 
 	generatorpkg "github.com/0glabs/evmchainbench/lib/generator"
+	"github.com/0glabs/evmchainbench/lib/store"
 )
 
-func GenTx(rpcUrl, faucetPrivateKey string, senderCount, txCount int, txType string, txStoreDir string) {
-	generator, err := generatorpkg.NewGenerator(rpcUrl, faucetPrivateKey, senderCount, txCount, true, txStoreDir)
+// GenTx generates the txs and writes them to the store at txStoreDir, at most
+// streamBuffer txs per sender signed ahead of the disk.
+func GenTx(rpcUrl, faucetPrivateKey string, senderCount, txCount int, txType string, txStoreDir, compression string, streamBuffer int, options generatorpkg.Options) {
+	err := store.ValidateCompression(compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	generator, err := generatorpkg.NewGenerator(rpcUrl, faucetPrivateKey, senderCount, txCount, true, txStoreDir, options)
 	if err != nil {
 		log.Fatalf("Failed to create generator: %v", err)
 	}
+	generator.Store.Compression = compression
+
+	manifest := generator.Manifest(txType)
 
-	switch txType {
-	case "simple":
-		_, err = generator.GenerateSimple()
-	case "erc20":
-		_, err = generator.GenerateERC20()
-	case "uniswap":
-		_, err = generator.GenerateUniswap()
-	default:
-		log.Fatalf("Transaction type \"%v\" is not valid", txType)
+	// the generator writes the txs to disk while they are streamed, they only
+	// need to be drained
+	stream, err := generator.Stream(txType, streamBuffer)
+	if err != nil {
+		log.Fatalf("Failed to generate transactions: %v", err)
 	}
+
+	var wg sync.WaitGroup
+	for _, txs := range stream.Txs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range txs {
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = <-stream.Err
 	if err != nil {
 		log.Fatalf("Failed to generate transactions: %v", err)
 	}
+
+	// the fees bumped while preparing are the ones the workload is signed with
+	generator.RecordFees(manifest)
+	manifest.Deadline = generator.Deadline
+	manifest.Compression = compression
+	err = generator.Store.WriteManifest(manifest)
+	if err != nil {
+		log.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	fmt.Println("Stored", generator.Store.Written())
 }
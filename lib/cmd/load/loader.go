@@ -2,65 +2,105 @@ package load
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/0glabs/evmchainbench/lib/cmd/run"
 	"github.com/0glabs/evmchainbench/lib/store"
-	"github.com/0glabs/evmchainbench/lib/util"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 type Loader struct {
-	RpcUrl string
-	Store  *store.Store
+	RpcUrl   string
+	WsRpcUrl string
+	Store    *store.Store
+	Options  run.Options
+	// Resume reads the tx files from the last checkpoint on and skips the
+	// txs of every sender below its pending nonce, so that an interrupted load
+	// continues instead of failing on used nonces.
+	Resume bool
 }
 
-func NewLoader(rpcUrl, txStoreDir string) *Loader {
+func NewLoader(rpcUrl, wsRpcUrl, txStoreDir string, resume bool, options run.Options) *Loader {
 	return &Loader{
-		RpcUrl: rpcUrl,
-		Store:  store.NewStore(txStoreDir),
+		RpcUrl:   rpcUrl,
+		WsRpcUrl: wsRpcUrl,
+		Store:    store.NewStore(txStoreDir),
+		Options:  options,
+		Resume:   resume,
 	}
 }
 
-func (l *Loader) LoadAndRun() error {
+// LoadAndRun replays the prepare txs of the store the chain has not seen yet,
+// then broadcasts its workload txs and measures them like run does. The
+// progress is checkpointed to the store while broadcasting.
+func (l *Loader) LoadAndRun() (run.Result, error) {
 	client, err := ethclient.Dial(l.RpcUrl)
 	if err != nil {
-		return err
+		return run.Result{}, err
 	}
 	defer client.Close()
 
-	txs, err := l.Store.LoadPrepareTxs()
+	manifest, err := l.validate(client)
 	if err != nil {
-		return err
+		return run.Result{}, err
 	}
 
-	for _, tx := range txs {
-		err = client.SendTransaction(context.Background(), tx)
+	err = l.replayPrepare(client, manifest)
+	if err != nil {
+		return run.Result{}, err
+	}
+
+	var point *resumePoint
+	var offsets []int64
+	if l.Resume {
+		paths, err := l.Store.TxFiles()
+		if err != nil {
+			return run.Result{}, err
+		}
+		point, err = l.readResumePoint(paths, func(sender common.Address) (uint64, error) {
+			return client.PendingNonceAt(context.Background(), sender)
+		})
 		if err != nil {
-			return err
+			return run.Result{}, err
 		}
+		offsets = point.offsets
 	}
 
-	err = util.WaitForReceiptsOfTxs(client, txs, 20*time.Second)
+	stream, err := l.Store.StreamTxs(offsets, l.Options.StreamBuffer)
 	if err != nil {
-		return err
+		return run.Result{}, err
 	}
 
-	txsMap, err := l.Store.LoadTxsMap()
-	if err != nil {
-		return err
+	streams := stream.Txs
+	var skipped []int
+	if l.Resume {
+		streams, err = point.resumeStreams(streams, stream.Offsets)
+		if err != nil {
+			return run.Result{}, err
+		}
+		skipped = point.skipped
 	}
 
-	// TODO, add limiter
-	transmitter, err := run.NewTransmitter(l.RpcUrl, nil)
-	if err != nil {
-		return err
+	// without a manifest the tx count is unknown, so no txs are sampled
+	options := l.Options
+	total := 0
+	if manifest != nil {
+		total = manifest.TxCount * len(manifest.Senders)
+	} else {
+		options.GasReportSamples = 0
 	}
 
-	err = transmitter.Broadcast(txsMap)
+	result, err := run.Measure(l.RpcUrl, l.WsRpcUrl, streams, stream.Err, total, options, func(transmitter *run.Transmitter) {
+		transmitter.CheckpointStore = l.Store
+		transmitter.Skipped = skipped
+		transmitter.Offsets = stream.Offsets
+	})
 	if err != nil {
-		return err
+		return run.Result{}, err
 	}
 
-	return nil
+	fmt.Println("Loaded", l.Store.Read())
+
+	return result, nil
 }
@@ -0,0 +1,239 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/store"
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// prepareReceiptTimeout bounds the wait for the receipts of one wave of
+// prepare txs.
+const prepareReceiptTimeout = 60 * time.Second
+
+type prepareTx struct {
+	tx     *types.Transaction
+	sender common.Address
+}
+
+// replayPrepare sends the prepare txs of the store that the chain has not
+// seen yet. Txs whose nonce is already used are skipped, either because they
+// were included by an earlier load or because another tx superseded them.
+//
+// Only the faucet funds other accounts, so the txs are replayed in waves that
+// end whenever a tx of another account follows a faucet tx. Within a wave the
+// txs of every sender are sent in order, but the senders concurrently.
+func (l *Loader) replayPrepare(client *ethclient.Client, manifest *store.Manifest) error {
+	txs, err := l.Store.LoadPrepareTxs()
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+
+	prepareTxs := make([]prepareTx, len(txs))
+	for i, tx := range txs {
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			return fmt.Errorf("failed to recover sender of prepare tx %s: %w", tx.Hash().Hex(), err)
+		}
+		prepareTxs[i] = prepareTx{tx: tx, sender: sender}
+	}
+
+	faucet := prepareTxs[0].sender
+	if manifest != nil {
+		faucet = manifest.Faucet.Address
+	}
+
+	var skipped, sent int
+	for start := 0; start < len(prepareTxs); {
+		end := start + 1
+		for end < len(prepareTxs) && !(prepareTxs[end-1].sender == faucet && prepareTxs[end].sender != faucet) {
+			end++
+		}
+
+		waveSkipped, waveSent, err := replayWave(client, prepareTxs[start:end])
+		if err != nil {
+			return err
+		}
+		skipped += waveSkipped
+		sent += waveSent
+		start = end
+	}
+
+	fmt.Printf("Prepare txs: %d sent, %d already used their nonce\n", sent, skipped)
+	return nil
+}
+
+// replayWave sends the txs of one wave and waits until they are included.
+func replayWave(client *ethclient.Client, wave []prepareTx) (int, int, error) {
+	bySender := make(map[common.Address]types.Transactions)
+	var senders []common.Address
+	for _, ptx := range wave {
+		if _, ok := bySender[ptx.sender]; !ok {
+			senders = append(senders, ptx.sender)
+		}
+		bySender[ptx.sender] = append(bySender[ptx.sender], ptx.tx)
+	}
+
+	var mutex sync.Mutex
+	var skipped int
+	var sent types.Transactions
+	var firstErr error
+	var wg sync.WaitGroup
+	for _, sender := range senders {
+		wg.Add(1)
+		go func(sender common.Address, txs types.Transactions) {
+			defer wg.Done()
+
+			senderSkipped, senderSent, err := replaySender(client, sender, txs)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			skipped += senderSkipped
+			sent = append(sent, senderSent...)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(sender, bySender[sender])
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return skipped, len(sent), firstErr
+	}
+
+	err := util.WaitForReceiptsOfTxs(client, sent, prepareReceiptTimeout)
+	if err != nil {
+		return skipped, len(sent), fmt.Errorf("failed to wait for prepare txs: %w", err)
+	}
+
+	for _, ptx := range wave {
+		if ptx.tx.To() == nil {
+			err = verifyDeployment(client, ptx)
+			if err != nil {
+				return skipped, len(sent), err
+			}
+		}
+	}
+
+	return skipped, len(sent), nil
+}
+
+// replaySender sends the txs of sender in nonce order, skipping those whose
+// nonce is already used. Txs found pending are returned with the sent ones,
+// so that they are waited for as well.
+func replaySender(client *ethclient.Client, sender common.Address, txs types.Transactions) (int, types.Transactions, error) {
+	nonce, err := client.NonceAt(context.Background(), sender, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get nonce of %s: %w", sender.Hex(), err)
+	}
+
+	var skipped int
+	var sent types.Transactions
+	for _, tx := range txs {
+		if tx.Nonce() < nonce {
+			err = reportUsedNonce(client, tx)
+			if err != nil {
+				return skipped, sent, err
+			}
+			skipped++
+			continue
+		}
+
+		err = client.SendTransaction(context.Background(), tx)
+		if err != nil && util.ClassifyError(err) == util.NonceTooLowClass {
+			// the nonce was used since it was checked, by tx itself if it is
+			// still pending
+			pending, err := isPending(client, tx)
+			if err != nil {
+				return skipped, sent, err
+			}
+			if pending {
+				sent = append(sent, tx)
+				continue
+			}
+			err = reportUsedNonce(client, tx)
+			if err != nil {
+				return skipped, sent, err
+			}
+			skipped++
+			continue
+		}
+		if err != nil && !util.IsAlreadyKnownError(err) {
+			return skipped, sent, fmt.Errorf("failed to send prepare tx %s: %w", tx.Hash().Hex(), err)
+		}
+
+		sent = append(sent, tx)
+	}
+
+	return skipped, sent, nil
+}
+
+// isPending tells whether the node holds tx in its mempool.
+func isPending(client *ethclient.Client, tx *types.Transaction) (bool, error) {
+	_, pending, err := client.TransactionByHash(context.Background(), tx.Hash())
+	if err == ethereum.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up prepare tx %s: %w", tx.Hash().Hex(), err)
+	}
+	return pending, nil
+}
+
+// reportUsedNonce tells whether tx itself or another tx used its nonce. tx
+// must not be pending.
+func reportUsedNonce(client *ethclient.Client, tx *types.Transaction) error {
+	receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+	if err == ethereum.NotFound {
+		fmt.Printf("Warning: prepare tx %s was superseded by another tx with nonce %d\n", tx.Hash().Hex(), tx.Nonce())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get receipt of prepare tx %s: %w", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		fmt.Printf("Warning: prepare tx %s was included but failed\n", tx.Hash().Hex())
+	}
+	return nil
+}
+
+// verifyDeployment checks that a contract creation produced the contract the
+// workload txs were generated for.
+func verifyDeployment(client *ethclient.Client, ptx prepareTx) error {
+	expected := crypto.CreateAddress(ptx.sender, ptx.tx.Nonce())
+
+	receipt, err := client.TransactionReceipt(context.Background(), ptx.tx.Hash())
+	if err == ethereum.NotFound {
+		return fmt.Errorf("contract %s was never deployed, its deployment %s was superseded", expected.Hex(), ptx.tx.Hash().Hex())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get receipt of deployment %s: %w", ptx.tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("deployment %s of contract %s failed", ptx.tx.Hash().Hex(), expected.Hex())
+	}
+	if receipt.ContractAddress != expected {
+		return fmt.Errorf("deployment %s created contract %s instead of %s", ptx.tx.Hash().Hex(), receipt.ContractAddress.Hex(), expected.Hex())
+	}
+
+	code, err := client.CodeAt(context.Background(), expected, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get code of contract %s: %w", expected.Hex(), err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("contract %s has no code", expected.Hex())
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/0glabs/evmchainbench/lib/store"
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// resumePoint is where the tx file of every sender continues after the last
+// checkpoint, how many of its txs come before that, and the pending nonce of
+// the sender.
+type resumePoint struct {
+	checkpoint *store.Checkpoint
+	offsets    []int64
+	skipped    []int
+	pending    []uint64
+}
+
+// readResumePoint reads the checkpoint of the store for the tx files at
+// paths and the pending nonces of their senders. A sender only continues at
+// its offset of the checkpoint if the chain is past the last tx sent, e.g. a
+// node restart may have dropped txs the checkpoint holds as sent. Other
+// senders start at their first tx, as do all without a checkpoint.
+func (l *Loader) readResumePoint(paths []string, pendingNonce func(sender common.Address) (uint64, error)) (*resumePoint, error) {
+	point := &resumePoint{
+		offsets: make([]int64, len(paths)),
+		skipped: make([]int, len(paths)),
+		pending: make([]uint64, len(paths)),
+	}
+
+	for index, path := range paths {
+		tx, err := store.ReadFirstTx(path)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover sender of tx %s: %w", tx.Hash().Hex(), err)
+		}
+		point.pending[index], err = pendingNonce(sender)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending nonce of %s: %w", sender.Hex(), err)
+		}
+	}
+
+	checkpoint, err := l.Store.ReadCheckpoint()
+	if errors.Is(err, os.ErrNotExist) {
+		return point, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(checkpoint.Senders) != len(paths) {
+		return nil, fmt.Errorf("checkpoint holds %d senders, but there are %d tx files", len(checkpoint.Senders), len(paths))
+	}
+	point.checkpoint = checkpoint
+
+	var sent, behind int
+	for index, sender := range checkpoint.Senders {
+		if !sender.Sent {
+			continue
+		}
+		sent += sender.Index + 1
+		if sender.Offset == 0 {
+			continue
+		}
+		if point.pending[index] <= sender.Nonce {
+			behind++
+			continue
+		}
+		point.offsets[index] = sender.Offset
+		point.skipped[index] = sender.Index + 1
+	}
+	fmt.Printf("Last checkpoint at %v: %d txs sent\n", checkpoint.UpdatedAt.Format(time.RFC3339), sent)
+	if behind > 0 {
+		fmt.Printf("Warning: the chain lost txs of %d senders after the checkpoint, they start over at their pending nonce\n", behind)
+	}
+
+	return point, nil
+}
+
+// resumeStreams drops the txs at the start of every stream whose nonce is
+// below the pending nonce of their sender, so that an interrupted load
+// continues where the chain stands. The streams start at the resume point,
+// so only the txs sent after the checkpoint are left to drop. The skipped
+// txs of the resume point and offsets, if not nil, are advanced past the
+// dropped txs.
+func (p *resumePoint) resumeStreams(streams []<-chan *types.Transaction, offsets []int64) ([]<-chan *types.Transaction, error) {
+	resumed := make([]<-chan *types.Transaction, len(streams))
+	var dropped int
+	for index, txs := range streams {
+		var first *types.Transaction
+		checked := false
+		for tx := range txs {
+			if !checked {
+				err := p.check(index, tx)
+				if err != nil {
+					return nil, err
+				}
+				checked = true
+			}
+			if tx.Nonce() >= p.pending[index] {
+				first = tx
+				break
+			}
+			p.skipped[index]++
+			if offsets != nil {
+				offsets[index] += store.FrameSize(tx)
+			}
+			dropped++
+		}
+
+		out := make(chan *types.Transaction, cap(txs))
+		resumed[index] = out
+		go func(txs <-chan *types.Transaction) {
+			defer close(out)
+			if first == nil {
+				return
+			}
+			out <- first
+			for tx := range txs {
+				out <- tx
+			}
+		}(txs)
+	}
+
+	var total int
+	for _, count := range p.skipped {
+		total += count
+	}
+	fmt.Printf("Resuming: skipped %d txs already sent, %d of them after the checkpoint\n", total, dropped)
+
+	return resumed, nil
+}
+
+// check makes sure the first tx read for the sender with the given index
+// follows the last tx of the checkpoint if the sender continues at its
+// offset, so a checkpoint of other tx files is never resumed from. Senders
+// starting over are not checked.
+func (p *resumePoint) check(index int, tx *types.Transaction) error {
+	if p.offsets[index] == 0 {
+		return nil
+	}
+
+	last := p.checkpoint.Senders[index].Nonce
+	if tx.Nonce() != last+1 {
+		return fmt.Errorf("tx file of sender %d continues with nonce %d after the checkpoint, but nonce %d was sent last", index, tx.Nonce(), last)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package load
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/0glabs/evmchainbench/lib/cmd/run"
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// writeSenders writes count txs with nonces from 0 on for every sender and
+// returns the addresses of the senders and the offset after every tx.
+func writeSenders(t *testing.T, s *store.Store, senders, count int) ([]common.Address, [][]int64) {
+	t.Helper()
+
+	signer := types.LatestSignerForChainID(big.NewInt(1337))
+	to := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	addresses := make([]common.Address, senders)
+	offsets := make([][]int64, senders)
+	for index := range addresses {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addresses[index] = crypto.PubkeyToAddress(key.PublicKey)
+
+		w, err := s.CreateTxsWriter(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for nonce := 0; nonce < count; nonce++ {
+			tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   big.NewInt(1337),
+				Nonce:     uint64(nonce),
+				GasTipCap: big.NewInt(1),
+				GasFeeCap: big.NewInt(2),
+				Gas:       21000,
+				To:        &to,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = w.Write(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offsets[index] = append(offsets[index], w.Offset())
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return addresses, offsets
+}
+
+func TestResumeBehindCheckpoint(t *testing.T) {
+	s := store.NewStore(t.TempDir())
+	addresses, offsets := writeSenders(t, s, 2, 10)
+
+	// both senders were checkpointed after nonce 5, but the chain lost the
+	// txs of the second one from nonce 3 on
+	err := s.WriteCheckpoint(&store.Checkpoint{
+		Senders: []store.SenderProgress{
+			{Sent: true, Index: 5, Nonce: 5, Offset: offsets[0][5]},
+			{Sent: true, Index: 5, Nonce: 5, Offset: offsets[1][5]},
+		},
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := map[common.Address]uint64{addresses[0]: 7, addresses[1]: 3}
+
+	l := &Loader{Store: s, Resume: true}
+	paths, err := s.TxFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := l.readResumePoint(paths, func(sender common.Address) (uint64, error) {
+		return pending[sender], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := s.StreamTxs(point.offsets, run.DefaultStreamBuffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams, err := point.resumeStreams(stream.Txs, stream.Offsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index, want := range []uint64{7, 3} {
+		tx, ok := <-streams[index]
+		if !ok {
+			t.Fatalf("sender %d has no txs left", index)
+		}
+		if tx.Nonce() != want {
+			t.Errorf("sender %d resumes at nonce %d, want %d", index, tx.Nonce(), want)
+		}
+		if point.skipped[index] != int(want) {
+			t.Errorf("sender %d skipped %d txs, want %d", index, point.skipped[index], want)
+		}
+		if stream.Offsets[index] != offsets[index][want-1] {
+			t.Errorf("sender %d resumes at offset %d, want %d", index, stream.Offsets[index], offsets[index][want-1])
+		}
+		for range streams[index] {
+		}
+	}
+	if err := <-stream.Err; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// validate checks the stored txs against the chain before anything is sent.
+// Mismatches that would get the txs rejected are errors, those that only make
+// them slow are printed as warnings. It returns the manifest of the store, nil
+// for stores written by older versions.
+func (l *Loader) validate(client *ethclient.Client) (*store.Manifest, error) {
+	manifest, err := l.Store.ReadManifest()
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("Warning: the tx store has no manifest, the txs cannot be validated")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Loading %s txs of %d senders generated at %v\n", manifest.TxType, len(manifest.Senders), manifest.CreatedAt.Format(time.RFC3339))
+
+	chainID, err := client.NetworkID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if chainID.Cmp(manifest.ChainID) != 0 {
+		return nil, fmt.Errorf("txs were generated for chain ID %v, but the node is on chain ID %v", manifest.ChainID, chainID)
+	}
+
+	paths, err := l.Store.TxFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) != len(manifest.Senders) {
+		return nil, fmt.Errorf("manifest lists %d senders, but there are %d tx files", len(manifest.Senders), len(paths))
+	}
+
+	accounts := append([]store.ManifestAccount{manifest.Faucet}, manifest.Senders...)
+	for _, account := range accounts {
+		nonce, err := client.PendingNonceAt(context.Background(), account.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending nonce of %s: %w", account.Address.Hex(), err)
+		}
+		// a higher nonce is fine, the prepare txs may have been replayed by
+		// an earlier load
+		if nonce < account.Nonce {
+			return nil, fmt.Errorf("txs of %s start at nonce %d, but its nonce is only %d on chain", account.Address.Hex(), account.Nonce, nonce)
+		}
+	}
+
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %w", err)
+	}
+	priceCap := manifest.PriceCap()
+	if header.BaseFee != nil && priceCap.Cmp(header.BaseFee) < 0 {
+		fmt.Printf("Warning: the txs pay at most %v per gas, below the current base fee of %v\n", priceCap, header.BaseFee)
+	} else {
+		gasPrice, err := client.SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %w", err)
+		}
+		if priceCap.Cmp(gasPrice) < 0 {
+			fmt.Printf("Warning: the txs pay at most %v per gas, below the suggested gas price of %v\n", priceCap, gasPrice)
+		}
+	}
+
+	if manifest.Deadline != 0 && time.Now().Unix() > manifest.Deadline {
+		return nil, fmt.Errorf("the txs expired at %v, generate them again with a later --swap-deadline", time.Unix(manifest.Deadline, 0).Format(time.RFC3339))
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,70 @@
+package retarget
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/account"
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// Retarget re-signs the stored txs for the chain at rpcUrl, starting at the
+// pending nonces of the faucet and senders there. The txs are compressed with
+// compression, or like before if it is empty.
+func Retarget(rpcUrl, faucetPrivateKey, txStoreDir, compression string) error {
+	client, err := ethclient.Dial(rpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	s := store.NewStore(txStoreDir)
+	manifest, err := s.ReadManifest()
+	if err != nil {
+		return fmt.Errorf("a manifest is needed to retarget the store: %w", err)
+	}
+	s.Compression = compression
+	if compression == "" {
+		s.Compression = manifest.Compression
+	}
+
+	chainID, err := client.NetworkID(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	faucet, err := account.CreateFaucetAccount(client, faucetPrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to create faucet account: %w", err)
+	}
+
+	options := store.RetargetOptions{
+		ChainID:      chainID,
+		Faucet:       faucet.PrivateKey,
+		FaucetNonce:  faucet.Nonce,
+		SenderNonces: make([]uint64, len(manifest.Senders)),
+	}
+	for i, sender := range manifest.Senders {
+		options.SenderNonces[i], err = client.PendingNonceAt(context.Background(), sender.Address)
+		if err != nil {
+			return fmt.Errorf("failed to get pending nonce of %s: %w", sender.Address.Hex(), err)
+		}
+	}
+
+	fmt.Printf("Retargeting from chain ID %v to chain ID %v\n", manifest.ChainID, chainID)
+	fmt.Printf("Faucet %s nonce %d -> %s nonce %d\n", manifest.Faucet.Address.Hex(), manifest.Faucet.Nonce, faucet.Address.Hex(), faucet.Nonce)
+
+	remap, err := s.Retarget(options)
+	if err != nil {
+		return err
+	}
+
+	for original, mapped := range remap {
+		fmt.Printf("Remapped %s -> %s\n", original.Hex(), mapped.Hex())
+	}
+	fmt.Println("Retargeted", s.Written())
+
+	return nil
+}
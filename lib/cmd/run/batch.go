@@ -0,0 +1,50 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// sendBatch sends txs in one JSON-RPC batch of eth_sendRawTransaction calls,
+// saving a round trip per tx. Every item fails on its own and is dealt with
+// like a tx sent by itself, a failure of the whole batch counts as a failure
+// of every item. An error is returned with the hash of its tx. sent is called
+// for every tx of txs with the tx that went out for it, see settle, in order.
+func (t *Transmitter) sendBatch(client *ethclient.Client, txs types.Transactions, sent func(tx, sent *types.Transaction)) error {
+	elems := make([]rpc.BatchElem, len(txs))
+	for i, tx := range txs {
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode tx %s: %w", tx.Hash().Hex(), err)
+		}
+		elems[i] = rpc.BatchElem{
+			Method: "eth_sendRawTransaction",
+			Args:   []interface{}{hexutil.Encode(data)},
+			Result: new(common.Hash),
+		}
+	}
+
+	sentAt := time.Now()
+	err := client.Client().BatchCallContext(context.Background(), elems)
+	if err != nil {
+		for i := range elems {
+			elems[i].Error = err
+		}
+	}
+
+	for i, tx := range txs {
+		out, err := t.settle(client, tx, sentAt, elems[i].Error)
+		if err != nil {
+			return fmt.Errorf("tx %s of batch: %w", tx.Hash().Hex(), err)
+		}
+		sent(tx, out)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// DefaultCheckpointInterval is how often a Transmitter with a checkpoint store
+// writes its progress unless told otherwise.
+const DefaultCheckpointInterval = 5 * time.Second
+
+// progress tracks the last tx sent of every stream.
+type progress struct {
+	mutex   sync.Mutex
+	senders []store.SenderProgress
+	// next is the index of the next tx of every stream in its tx file
+	next []int
+	// offsets, if not nil, is the offset of the next tx of every stream in
+	// its tx file
+	offsets []int64
+	// gaps, if not nil, tracks the txs in flight to detect nonce gaps.
+	gaps *nonceGaps
+}
+
+// newProgress starts tracking streams whose first skipped[i] txs are left
+// out and which start at offsets[i] of their tx files. skipped and offsets
+// may be nil.
+func newProgress(streams int, skipped []int, offsets []int64) *progress {
+	p := &progress{
+		senders: make([]store.SenderProgress, streams),
+		next:    make([]int, streams),
+	}
+	copy(p.next, skipped)
+	if offsets != nil {
+		p.offsets = make([]int64, streams)
+		copy(p.offsets, offsets)
+	}
+	return p
+}
+
+// sent records that the stream is done with tx. sent is the tx that went out
+// for it, nil if tx was skipped.
+func (p *progress) sent(stream int, tx, sent *types.Transaction) {
+	p.mutex.Lock()
+	p.senders[stream] = store.SenderProgress{Sent: true, Index: p.next[stream], Nonce: tx.Nonce()}
+	p.next[stream]++
+	if p.offsets != nil {
+		p.offsets[stream] += store.FrameSize(tx)
+		p.senders[stream].Offset = p.offsets[stream]
+	}
+	p.mutex.Unlock()
+
+	if p.gaps != nil && sent != nil {
+		p.gaps.sent(stream, sent)
+	}
+}
+
+func (p *progress) checkpoint() *store.Checkpoint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return &store.Checkpoint{
+		Senders:   append([]store.SenderProgress(nil), p.senders...),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// checkpointPeriodically writes the progress to the checkpoint store of t
+// until done is closed, then writes it a last time.
+func (t *Transmitter) checkpointPeriodically(p *progress, done <-chan struct{}) error {
+	interval := t.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := t.CheckpointStore.WriteCheckpoint(p.checkpoint())
+			if err != nil {
+				return fmt.Errorf("failed to write checkpoint: %w", err)
+			}
+		case <-done:
+			return t.CheckpointStore.WriteCheckpoint(p.checkpoint())
+		}
+	}
+}
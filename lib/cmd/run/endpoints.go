@@ -0,0 +1,84 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Strategies distributing txs over several RPC endpoints.
+const (
+	// RoundRobinStrategy sends every tx to the endpoint after the one the
+	// previous tx of any sender went to.
+	RoundRobinStrategy = "round-robin"
+	// SenderStrategy pins every sender to one endpoint.
+	SenderStrategy = "sender"
+	// RandomStrategy sends every tx to a random endpoint.
+	RandomStrategy = "random"
+)
+
+func ValidateRpcStrategy(strategy string) error {
+	switch strategy {
+	case "", RoundRobinStrategy, SenderStrategy, RandomStrategy:
+		return nil
+	default:
+		return fmt.Errorf("RPC strategy \"%v\" is not valid", strategy)
+	}
+}
+
+// endpoints holds one client per RPC endpoint, shared by all workers of a
+// Transmitter.
+type endpoints struct {
+	clients  []*ethclient.Client
+	strategy string
+	// next counts the txs distributed round-robin.
+	next atomic.Uint64
+}
+
+// dialEndpoints connects to every url. The HTTP connections to every endpoint
+// are pooled and limited to connections.
+func dialEndpoints(urls []string, strategy string, connections int) (*endpoints, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = connections
+	transport.MaxIdleConnsPerHost = connections
+	httpClient := &http.Client{Transport: transport}
+
+	e := &endpoints{strategy: strategy}
+	for _, url := range urls {
+		client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPClient(httpClient))
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to dial %s: %w", url, err)
+		}
+		e.clients = append(e.clients, ethclient.NewClient(client))
+	}
+	return e, nil
+}
+
+// client returns the client the next txs of the stream with the given index
+// go to.
+func (e *endpoints) client(stream int) *ethclient.Client {
+	if len(e.clients) == 1 {
+		return e.clients[0]
+	}
+
+	switch e.strategy {
+	case SenderStrategy:
+		return e.clients[stream%len(e.clients)]
+	case RandomStrategy:
+		return e.clients[rand.Intn(len(e.clients))]
+	default:
+		return e.clients[e.next.Add(1)%uint64(len(e.clients))]
+	}
+}
+
+func (e *endpoints) Close() {
+	for _, client := range e.clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,129 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// ErrorPolicy is how a Transmitter deals with a class of errors of sending a
+// tx.
+type ErrorPolicy string
+
+const (
+	// SkipPolicy moves on to the next tx. A tx the node already knows counts
+	// as sent, any other is dropped.
+	SkipPolicy ErrorPolicy = "skip"
+	// RetryPolicy sends the tx again after a backoff.
+	RetryPolicy ErrorPolicy = "retry"
+	// ResyncPolicy moves on if the pending nonce of the sender of the tx is
+	// past it, counting the tx as sent if the node knows it, and retries the
+	// tx otherwise.
+	ResyncPolicy ErrorPolicy = "resync"
+	// AbortPolicy stops the broadcast.
+	AbortPolicy ErrorPolicy = "abort"
+)
+
+const (
+	DefaultMaxRetries   = 5
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// maxRetryBackoff caps the backoff doubling with every retry.
+	maxRetryBackoff = 5 * time.Second
+)
+
+// DefaultErrorPolicies are the policies of the classes not configured
+// otherwise. Underpriced txs are bumped first if they can be re-signed.
+var DefaultErrorPolicies = map[util.ErrorClass]ErrorPolicy{
+	util.AlreadyKnownClass:      SkipPolicy,
+	util.NonceTooLowClass:       ResyncPolicy,
+	util.NonceGapClass:          RetryPolicy,
+	util.UnderpricedClass:       AbortPolicy,
+	util.TxpoolFullClass:        RetryPolicy,
+	util.InsufficientFundsClass: AbortPolicy,
+	util.TransportClass:         RetryPolicy,
+	util.OtherClass:             AbortPolicy,
+}
+
+// ParseErrorPolicies turns a map of class names to policy names into error
+// policies, on top of the default ones.
+func ParseErrorPolicies(policies map[string]string) (map[util.ErrorClass]ErrorPolicy, error) {
+	parsed := make(map[util.ErrorClass]ErrorPolicy, len(DefaultErrorPolicies))
+	for class, policy := range DefaultErrorPolicies {
+		parsed[class] = policy
+	}
+
+	for class, policy := range policies {
+		if _, ok := DefaultErrorPolicies[util.ErrorClass(class)]; !ok {
+			return nil, fmt.Errorf("error class \"%v\" is not valid", class)
+		}
+		switch ErrorPolicy(policy) {
+		case SkipPolicy, RetryPolicy, ResyncPolicy, AbortPolicy:
+		default:
+			return nil, fmt.Errorf("error policy \"%v\" is not valid", policy)
+		}
+		parsed[util.ErrorClass(class)] = ErrorPolicy(policy)
+	}
+	return parsed, nil
+}
+
+func (t *Transmitter) errorPolicy(class util.ErrorClass) ErrorPolicy {
+	if policy, ok := t.ErrorPolicies[class]; ok {
+		return policy
+	}
+	return DefaultErrorPolicies[class]
+}
+
+// retry waits before the attempt-th retry of a tx. It returns false once the
+// retries are used up.
+func (t *Transmitter) retry(attempt int) bool {
+	maxRetries := t.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	if attempt >= maxRetries {
+		return false
+	}
+
+	backoff := t.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	// the backoff doubles with every attempt until it reaches the cap, so a
+	// high attempt never shifts it past the range of a duration
+	delay := backoff
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay <<= 1
+	}
+	time.Sleep(min(delay, maxRetryBackoff))
+	return true
+}
+
+// nonceUsed reports whether the pending nonce of the sender of tx is past
+// the nonce of tx, i.e. tx or a tx replacing it is already known.
+func nonceUsed(client *ethclient.Client, tx *types.Transaction) (bool, error) {
+	sender, err := util.SenderOf(tx)
+	if err != nil {
+		return false, err
+	}
+	nonce, err := client.PendingNonceAt(context.Background(), sender)
+	if err != nil {
+		return false, err
+	}
+	return tx.Nonce() < nonce, nil
+}
+
+// txKnown reports whether the node knows tx itself, pending or included.
+func txKnown(client *ethclient.Client, tx *types.Transaction) (bool, error) {
+	_, _, err := client.TransactionByHash(context.Background(), tx.Hash())
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -6,8 +6,11 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	limiterpkg "github.com/0glabs/evmchainbench/lib/limiter"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/websocket"
 )
 
@@ -24,8 +27,11 @@ type EthereumListener struct {
 	limiter          *limiterpkg.RateLimiter
 	blockStat        []BlockInfo
 	quit             chan struct{}
+	closeOnce        sync.Once
 	bestTPS          int64
 	gasUsedAtBestTPS float64
+	// Latency, if not nil, learns about the txs of every block.
+	Latency *LatencyTracker
 }
 
 func NewEthereumListener(wsURL string, limiter *limiterpkg.RateLimiter) *EthereumListener {
@@ -121,6 +127,15 @@ func (el *EthereumListener) handleBlockResponse(response map[string]interface{})
 	if result, ok := response["result"].(map[string]interface{}); ok {
 		if txns, ok := result["transactions"].([]interface{}); ok {
 			el.limiter.IncreaseLimit(len(txns))
+			if el.Latency != nil {
+				hashes := make([]common.Hash, 0, len(txns))
+				for _, txn := range txns {
+					if hash, ok := txn.(string); ok {
+						hashes = append(hashes, common.HexToHash(hash))
+					}
+				}
+				el.Latency.Included(hashes, time.Now())
+			}
 			ts, _ := strconv.ParseInt(result["timestamp"].(string)[2:], 16, 64)
 			gasUsed, _ := strconv.ParseInt(result["gasUsed"].(string)[2:], 16, 64)
 			gasLimit, _ := strconv.ParseInt(result["gasLimit"].(string)[2:], 16, 64)
@@ -187,9 +202,20 @@ func (el *EthereumListener) handleBlockResponse(response map[string]interface{})
 	}
 }
 
+// Close stops listening. It may be called more than once.
 func (el *EthereumListener) Close() {
-	if el.conn != nil {
-		el.conn.Close()
+	el.closeOnce.Do(func() {
+		if el.conn != nil {
+			el.conn.Close()
+		}
+		close(el.quit)
+	})
+}
+
+// Result returns the best TPS seen so far and the gas usage at that TPS.
+func (el *EthereumListener) Result() Result {
+	return Result{
+		BestTPS:          el.bestTPS,
+		GasUsedAtBestTPS: el.gasUsedAtBestTPS,
 	}
-	close(el.quit)
 }
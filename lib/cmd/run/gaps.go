@@ -0,0 +1,145 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// DefaultNonceGapInterval is how often a Transmitter filling nonce gaps checks
+// the pending nonces of its senders unless told otherwise.
+const DefaultNonceGapInterval = 10 * time.Second
+
+// gapResendCount is the number of txs in flight sent again at once when a
+// sender is stuck, since the txs queued right behind a dropped one are often
+// dropped as well.
+const gapResendCount = 64
+
+// nonceGaps keeps the txs of every stream the chain has not yet counted in
+// the pending nonce of their sender. A sender whose pending nonce stops at a
+// nonce it sent, or skipped, is stuck behind a dropped tx.
+type nonceGaps struct {
+	mutex   sync.Mutex
+	streams []gapStream
+}
+
+type gapStream struct {
+	sender   common.Address
+	known    bool
+	inflight types.Transactions
+	// pending is the pending nonce seen by the last check, if checked
+	pending uint64
+	checked bool
+}
+
+func newNonceGaps(streams int) *nonceGaps {
+	return &nonceGaps{streams: make([]gapStream, streams)}
+}
+
+func (g *nonceGaps) sent(stream int, tx *types.Transaction) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	s := &g.streams[stream]
+	if !s.known {
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			return
+		}
+		s.sender, s.known = sender, true
+	}
+	s.inflight = append(s.inflight, tx)
+}
+
+// fillNonceGapsPeriodically checks the senders of all streams every interval
+// until done is closed, and closes the gaps of stuck ones.
+func (t *Transmitter) fillNonceGapsPeriodically(gaps *nonceGaps, endpoints *endpoints, done <-chan struct{}) {
+	interval := t.NonceGapInterval
+	if interval <= 0 {
+		interval = DefaultNonceGapInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for stream := range gaps.streams {
+				err := t.fillNonceGap(gaps, stream, endpoints)
+				if err != nil {
+					fmt.Printf("Failed to fill nonce gap of stream %d: %v\n", stream, err)
+				}
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// fillNonceGap checks the pending nonce of the sender of the stream. Once it
+// is stuck, the nonces skipped before the first tx in flight are filled by
+// the NonceFiller, if any, and the txs in flight are sent again.
+func (t *Transmitter) fillNonceGap(gaps *nonceGaps, stream int, endpoints *endpoints) error {
+	gaps.mutex.Lock()
+	s := &gaps.streams[stream]
+	sender, known := s.sender, s.known
+	gaps.mutex.Unlock()
+	if !known {
+		return nil
+	}
+
+	client := endpoints.client(stream)
+	pending, err := client.PendingNonceAt(context.Background(), sender)
+	if err != nil {
+		return err
+	}
+
+	gaps.mutex.Lock()
+	drop := 0
+	for drop < len(s.inflight) && s.inflight[drop].Nonce() < pending {
+		drop++
+	}
+	s.inflight = s.inflight[drop:]
+	stuck := s.checked && s.pending == pending && len(s.inflight) > 0
+	s.pending, s.checked = pending, true
+	var resend types.Transactions
+	if stuck {
+		resend = append(resend, s.inflight[:min(gapResendCount, len(s.inflight))]...)
+	}
+	gaps.mutex.Unlock()
+	if !stuck {
+		return nil
+	}
+
+	first := resend[0].Nonce()
+	fmt.Printf("Filling nonce gap [%d, %d) of %s\n", pending, first+uint64(len(resend)), sender.Hex())
+	for nonce := pending; nonce < first; nonce++ {
+		if t.NonceFiller == nil {
+			return fmt.Errorf("nonce %d of %s was skipped and cannot be filled without its private key", nonce, sender.Hex())
+		}
+		filler, err := t.NonceFiller(sender, nonce)
+		if err != nil {
+			return err
+		}
+		err = broadcast(client, filler)
+		if err != nil && !util.IsAlreadyKnownError(err) {
+			return fmt.Errorf("failed to fill nonce %d of %s: %w", nonce, sender.Hex(), err)
+		}
+		t.Stats.addGapFill()
+	}
+	for _, tx := range resend {
+		err = broadcast(client, tx)
+		if err != nil && !util.IsAlreadyKnownError(err) {
+			return fmt.Errorf("failed to send tx %s of %s again: %w", tx.Hash().Hex(), sender.Hex(), err)
+		}
+		t.Stats.addGapFill()
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package run
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ReportGasUsage compares the gas limit of the sampled txs with the gas used
+// according to their receipts. Chains building blocks by gas limit lose
+// throughput to every unit of unused gas limit.
+func ReportGasUsage(rpcUrl string, samples types.Transactions) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	client, err := ethclient.Dial(rpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var count, missing, failed int
+	var totalLimit, totalUsed uint64
+	minRatio, maxRatio := 1.0, 0.0
+	for _, tx := range samples {
+		receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+		if err == ethereum.NotFound {
+			missing++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			failed++
+		}
+
+		ratio := float64(receipt.GasUsed) / float64(tx.Gas())
+		minRatio = min(minRatio, ratio)
+		maxRatio = max(maxRatio, ratio)
+		totalLimit += tx.Gas()
+		totalUsed += receipt.GasUsed
+		count++
+	}
+
+	if count == 0 {
+		fmt.Printf("Gas report: no receipts found for %d sampled txs\n", missing)
+		return nil
+	}
+
+	fmt.Printf("Gas report: %d receipts, %d missing, %d failed\n", count, missing, failed)
+	fmt.Printf("  Avg gas limit: %d Avg gas used: %d\n", totalLimit/uint64(count), totalUsed/uint64(count))
+	fmt.Printf("  Gas used/limit: %.2f%% (min %.2f%% max %.2f%%)\n",
+		float64(totalUsed)/float64(totalLimit)*100, minRatio*100, maxRatio*100)
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package run
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LatencyTracker measures the time from sending a tx until the listener sees
+// it in a block.
+type LatencyTracker struct {
+	mutex    sync.Mutex
+	sent     map[common.Hash]time.Time
+	included []inclusion
+}
+
+type inclusion struct {
+	sentAt     time.Time
+	includedAt time.Time
+}
+
+// LatencySummary holds the percentiles of the measured latencies. Pending is
+// the number of sent txs never seen in a block.
+type LatencySummary struct {
+	Count   int
+	Pending int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		sent: make(map[common.Hash]time.Time),
+	}
+}
+
+// Sent records that the tx with the given hash was sent at the given time.
+func (lt *LatencyTracker) Sent(hash common.Hash, at time.Time) {
+	lt.mutex.Lock()
+	lt.sent[hash] = at
+	lt.mutex.Unlock()
+}
+
+// Included records the txs of a block received at the given time. Txs not
+// sent by us are ignored.
+func (lt *LatencyTracker) Included(hashes []common.Hash, at time.Time) {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	for _, hash := range hashes {
+		if sent, ok := lt.sent[hash]; ok {
+			lt.included = append(lt.included, inclusion{sentAt: sent, includedAt: at})
+			delete(lt.sent, hash)
+		}
+	}
+}
+
+func (lt *LatencyTracker) Summary() LatencySummary {
+	return lt.SummaryBetween(time.Time{}, time.Now().Add(time.Hour))
+}
+
+// SummaryBetween summarizes the latencies of the txs sent in [from, to).
+func (lt *LatencyTracker) SummaryBetween(from, to time.Time) LatencySummary {
+	inWindow := func(at time.Time) bool {
+		return !at.Before(from) && at.Before(to)
+	}
+
+	lt.mutex.Lock()
+	var latencies []time.Duration
+	for _, in := range lt.included {
+		if inWindow(in.sentAt) {
+			latencies = append(latencies, in.includedAt.Sub(in.sentAt))
+		}
+	}
+	var pending int
+	for _, sentAt := range lt.sent {
+		if inWindow(sentAt) {
+			pending++
+		}
+	}
+	lt.mutex.Unlock()
+
+	summary := LatencySummary{Count: len(latencies), Pending: pending}
+	if len(latencies) == 0 {
+		return summary
+	}
+
+	slices.Sort(latencies)
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	summary.P50 = percentile(0.50)
+	summary.P95 = percentile(0.95)
+	summary.P99 = percentile(0.99)
+	summary.Max = latencies[len(latencies)-1]
+	return summary
+}
+
+// IncludedBetween counts our txs included in [from, to), whenever they were
+// sent.
+func (lt *LatencyTracker) IncludedBetween(from, to time.Time) int {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	var count int
+	for _, in := range lt.included {
+		if !in.includedAt.Before(from) && in.includedAt.Before(to) {
+			count++
+		}
+	}
+	return count
+}
+
+// IncludedRate returns the rate our txs were included at from start until the
+// last of them was. A chain that falls behind keeps including a backlog after
+// sending stopped, which lowers the rate below the offered one.
+func (lt *LatencyTracker) IncludedRate(start time.Time) float64 {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	var last time.Time
+	for _, in := range lt.included {
+		if in.includedAt.After(last) {
+			last = in.includedAt
+		}
+	}
+	span := last.Sub(start).Seconds()
+	if span <= 0 {
+		return 0
+	}
+	return float64(len(lt.included)) / span
+}
+
+func (s LatencySummary) String() string {
+	return fmt.Sprintf("Latency: %d included, %d pending, p50 %v p95 %v p99 %v max %v", s.Count, s.Pending,
+		s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.P99.Round(time.Millisecond), s.Max.Round(time.Millisecond))
+}
@@ -0,0 +1,242 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	limiterpkg "github.com/0glabs/evmchainbench/lib/limiter"
+)
+
+// Shapes of load profile phases. Every phase starts at TPS:
+//   - constant stays at TPS
+//   - ramp moves linearly from TPS to To
+//   - step adds Step every Every
+//   - spike jumps to Peak for Length, At into the phase
+//   - sine swings by Amplitude around TPS with the given Period
+const (
+	ConstantShape = "constant"
+	RampShape     = "ramp"
+	StepShape     = "step"
+	SpikeShape    = "spike"
+	SineShape     = "sine"
+)
+
+// profileTick is how often the rate of a profile is updated.
+const profileTick = 100 * time.Millisecond
+
+// burstOf returns the burst of a pacer at rate: up to 100ms of txs are sent
+// at once to catch up.
+func burstOf(rate float64) int {
+	return int(rate / 10)
+}
+
+// Duration is a time.Duration written like "5m" in JSON.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Phase is a part of a load profile, see the shapes above.
+type Phase struct {
+	Name      string   `json:"name"`
+	Shape     string   `json:"shape"`
+	Duration  Duration `json:"duration"`
+	TPS       float64  `json:"tps"`
+	To        float64  `json:"to"`
+	Step      float64  `json:"step"`
+	Every     Duration `json:"every"`
+	Peak      float64  `json:"peak"`
+	At        Duration `json:"at"`
+	Length    Duration `json:"length"`
+	Amplitude float64  `json:"amplitude"`
+	Period    Duration `json:"period"`
+}
+
+// LoadProfile shapes the offered load over time, phase after phase. It is
+// read from a JSON file like
+//
+//	{"phases": [
+//	  {"name": "warmup", "shape": "ramp", "tps": 100, "to": 2000, "duration": "5m"},
+//	  {"shape": "step", "tps": 2000, "step": 200, "every": "60s", "duration": "5m"},
+//	  {"shape": "spike", "tps": 2000, "peak": 5000, "at": "30s", "length": "10s", "duration": "1m"}
+//	]}
+type LoadProfile struct {
+	Phases []Phase `json:"phases"`
+}
+
+// PhaseResult is what was measured during one phase of a load profile.
+type PhaseResult struct {
+	Name        string
+	OfferedTPS  float64
+	IncludedTPS float64
+	Latency     LatencySummary
+}
+
+func ReadLoadProfile(path string) (*LoadProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile LoadProfile
+	err = json.Unmarshal(data, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse load profile %s: %w", path, err)
+	}
+
+	if len(profile.Phases) == 0 {
+		return nil, fmt.Errorf("load profile %s has no phases", path)
+	}
+	for i := range profile.Phases {
+		phase := &profile.Phases[i]
+		if phase.Name == "" {
+			phase.Name = fmt.Sprintf("%d-%s", i+1, phase.Shape)
+		}
+		err = phase.validate()
+		if err != nil {
+			return nil, fmt.Errorf("phase %s: %w", phase.Name, err)
+		}
+	}
+
+	return &profile, nil
+}
+
+func (p *Phase) validate() error {
+	if p.Duration.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+
+	switch p.Shape {
+	case ConstantShape, RampShape, SpikeShape:
+	case StepShape:
+		if p.Every.Duration <= 0 {
+			return fmt.Errorf("step needs a positive every")
+		}
+	case SineShape:
+		if p.Period.Duration <= 0 {
+			return fmt.Errorf("sine needs a positive period")
+		}
+	default:
+		return fmt.Errorf("shape \"%v\" is not valid", p.Shape)
+	}
+	return nil
+}
+
+// rate returns the TPS of the phase elapsed into it.
+func (p *Phase) rate(elapsed time.Duration) float64 {
+	var rate float64
+	switch p.Shape {
+	case ConstantShape:
+		rate = p.TPS
+	case RampShape:
+		rate = p.TPS + (p.To-p.TPS)*elapsed.Seconds()/p.Duration.Seconds()
+	case StepShape:
+		rate = p.TPS + p.Step*float64(elapsed/p.Every.Duration)
+	case SpikeShape:
+		rate = p.TPS
+		if elapsed >= p.At.Duration && elapsed < p.At.Duration+p.Length.Duration {
+			rate = p.Peak
+		}
+	case SineShape:
+		rate = p.TPS + p.Amplitude*math.Sin(2*math.Pi*elapsed.Seconds()/p.Period.Seconds())
+	}
+	return max(0, rate)
+}
+
+// Rate returns the TPS elapsed into the profile and the index of its phase,
+// or -1 once the profile is over.
+func (lp *LoadProfile) Rate(elapsed time.Duration) (float64, int) {
+	for i := range lp.Phases {
+		phase := &lp.Phases[i]
+		if elapsed < phase.Duration.Duration {
+			return phase.rate(elapsed), i
+		}
+		elapsed -= phase.Duration.Duration
+	}
+	return 0, -1
+}
+
+// drive adjusts the rate of bucket to the profile started at start, and stops
+// the bucket once the profile is over or done is closed.
+func (lp *LoadProfile) drive(bucket *limiterpkg.TokenBucket, start time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(profileTick)
+	defer ticker.Stop()
+
+	current := -1
+	for {
+		rate, phase := lp.Rate(time.Since(start))
+		if phase < 0 {
+			fmt.Println("Load profile finished")
+			bucket.Stop()
+			return
+		}
+		if phase != current {
+			current = phase
+			fmt.Printf("Load profile phase %s (%s) for %v\n", lp.Phases[phase].Name, lp.Phases[phase].Shape, lp.Phases[phase].Duration)
+		}
+		bucket.SetRate(rate, burstOf(rate))
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// results breaks the measurements down by the phases of the profile started
+// at start. Phases the broadcast did not reach are left out, and a phase the
+// broadcast ended in is measured up to end.
+func (lp *LoadProfile) results(latency *LatencyTracker, start, end time.Time) []PhaseResult {
+	var results []PhaseResult
+	from := start
+	for _, phase := range lp.Phases {
+		if !from.Before(end) {
+			break
+		}
+		to := from.Add(phase.Duration.Duration)
+		if to.After(end) {
+			to = end
+		}
+
+		summary := latency.SummaryBetween(from, to)
+		seconds := to.Sub(from).Seconds()
+		results = append(results, PhaseResult{
+			Name:        phase.Name,
+			OfferedTPS:  float64(summary.Count+summary.Pending) / seconds,
+			IncludedTPS: float64(latency.IncludedBetween(from, to)) / seconds,
+			Latency:     summary,
+		})
+		from = to
+	}
+	return results
+}
+
+// FormatPhaseResults renders phase results as a table.
+func FormatPhaseResults(results []PhaseResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %12s %12s %10s %10s %10s %8s\n", "Phase", "Offered TPS", "Included TPS", "p50", "p95", "p99", "Pending")
+	for _, r := range results {
+		fmt.Fprintf(&b, "%-16s %12.0f %12.0f %10v %10v %10v %8d\n", r.Name, r.OfferedTPS, r.IncludedTPS,
+			r.Latency.P50.Round(time.Millisecond), r.Latency.P95.Round(time.Millisecond), r.Latency.P99.Round(time.Millisecond), r.Latency.Pending)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
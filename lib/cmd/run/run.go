@@ -1,58 +1,253 @@
 package run
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/0glabs/evmchainbench/lib/generator"
 	limiterpkg "github.com/0glabs/evmchainbench/lib/limiter"
+	"github.com/0glabs/evmchainbench/lib/util"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
-func Run(httpRpc, wsRpc, faucetPrivateKey string, senderCount, txCount int, txType string, mempool int) {
-	generator, err := generator.NewGenerator(httpRpc, faucetPrivateKey, senderCount, txCount, false, "")
+// ErrBroadcast wraps the errors of sending txs, as opposed to the errors of
+// setting up a run.
+var ErrBroadcast = errors.New("failed to broadcast transactions")
+
+// DefaultStreamBuffer is the number of txs per sender produced ahead of
+// sending them unless told otherwise.
+const DefaultStreamBuffer = 1000
+
+// Options holds the knobs of transmission and measurement.
+type Options struct {
+	Mempool          int
+	GasReportSamples int
+	// Stream sends txs while they are generated instead of generating all of
+	// them first. StreamBuffer is the number of txs per sender waiting to be
+	// sent, whether they are generated or loaded.
+	Stream       bool
+	StreamBuffer int
+	// TargetTPS, if positive, sends txs open-loop at this rate instead of
+	// keeping the mempool filled.
+	TargetTPS float64
+	// LoadProfile, if set, is the path of a load profile to send txs
+	// open-loop by, see LoadProfile.
+	LoadProfile string
+	// BatchSize, if above 1, sends the txs of every sender in JSON-RPC
+	// batches of this size.
+	BatchSize int
+	// RpcUrls are the HTTP endpoints txs are distributed over by
+	// RpcStrategy, the endpoint of the run is used if there are none.
+	RpcUrls     []string
+	RpcStrategy string
+	// Workers is the number of txs in flight at once, one per sender if
+	// not positive. Connections limits the HTTP connections per endpoint,
+	// one per worker if not positive.
+	Workers     int
+	Connections int
+	// ErrorPolicies maps error classes to the policies dealing with them, on
+	// top of DefaultErrorPolicies. MaxRetries and RetryBackoff bound the
+	// retries, the defaults apply if they are not positive.
+	ErrorPolicies map[string]string
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	// FillNonceGaps sends the txs of senders stuck behind a dropped tx again.
+	FillNonceGaps bool
+}
+
+// Result is what a benchmark run measured.
+type Result struct {
+	BestTPS          int64
+	GasUsedAtBestTPS float64
+	Latency          LatencySummary
+	// OfferedTPS is the rate txs were sent at, IncludedTPS the rate they
+	// were included at until the last of them was.
+	OfferedTPS  float64
+	IncludedTPS float64
+	// Errors counts the errors of sending txs by their class.
+	Errors map[util.ErrorClass]int64
+	// Phases breaks the measurements down by the phases of the load profile.
+	Phases []PhaseResult
+}
+
+func Run(httpRpc, wsRpc, faucetPrivateKey string, senderCount, txCount int, txType string, options Options, generatorOptions generator.Options) {
+	_, err := Benchmark(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, options, generatorOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Benchmark generates a workload, broadcasts it and waits until the chain has
+// processed it.
+func Benchmark(httpRpc, wsRpc, faucetPrivateKey string, senderCount, txCount int, txType string, options Options, generatorOptions generator.Options) (Result, error) {
+	generator, err := generator.NewGenerator(httpRpc, faucetPrivateKey, senderCount, txCount, false, "", generatorOptions)
 	if err != nil {
-		log.Fatalf("Failed to create generator: %v", err)
+		return Result{}, fmt.Errorf("failed to create generator: %w", err)
+	}
+
+	var streams []<-chan *types.Transaction
+	var streamErr <-chan error
+	if options.Stream {
+		stream, err := generator.Stream(txType, options.StreamBuffer)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to generate transactions: %w", err)
+		}
+		streams, streamErr = stream.Txs, stream.Err
+	} else {
+		txsMap, err := generator.Generate(txType)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to generate transactions: %w", err)
+		}
+		streams = streamsOf(txsMap)
 	}
 
-	var txsMap map[int]types.Transactions
+	return Measure(httpRpc, wsRpc, streams, streamErr, senderCount*txCount, options, func(transmitter *Transmitter) {
+		transmitter.Resigner = generator.Resign
+		transmitter.MaxFeeBumps = generator.FeeStrategy.MaxBumps
+		transmitter.NonceFiller = generator.FillerTx
+	})
+}
+
+// Measure broadcasts the txs of the streams, total in all, while listening for
+// the TPS the chain achieves, and reports the gas usage of sampled txs after.
+// A non-nil streamErr reports whether producing the streams failed. configure,
+// if not nil, sets up the transmitter before anything is sent. Run and load
+// both measure with it, so their numbers are comparable. If measuring fails,
+// the rest of the streams is read so that their producers finish.
+func Measure(httpRpc, wsRpc string, streams []<-chan *types.Transaction, streamErr <-chan error, total int, options Options, configure func(transmitter *Transmitter)) (_ Result, err error) {
+	defer func() {
+		if err != nil {
+			drainStreams(streams)
+			if streamErr != nil {
+				<-streamErr
+			}
+		}
+	}()
 
-	switch txType {
-	case "simple":
-		txsMap, err = generator.GenerateSimple()
-	case "erc20":
-		txsMap, err = generator.GenerateERC20()
-	case "uniswap":
-		txsMap, err = generator.GenerateUniswap()
-	default:
-		log.Fatalf("Transaction type \"%v\" is not valid", txType)
+	err = ValidateRpcStrategy(options.RpcStrategy)
+	if err != nil {
+		return Result{}, err
 	}
+
+	errorPolicies, err := ParseErrorPolicies(options.ErrorPolicies)
 	if err != nil {
-		log.Fatalf("Failed to generate transactions: %v", err)
+		return Result{}, err
+	}
+
+	var profile *LoadProfile
+	if options.LoadProfile != "" {
+		if options.TargetTPS > 0 {
+			return Result{}, fmt.Errorf("a target TPS and a load profile cannot be combined")
+		}
+		profile, err = ReadLoadProfile(options.LoadProfile)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to read load profile: %w", err)
+		}
 	}
 
-	limiter := limiterpkg.NewRateLimiter(mempool)
+	limiter := limiterpkg.NewRateLimiter(options.Mempool)
+
+	latency := NewLatencyTracker()
 
 	ethListener := NewEthereumListener(wsRpc, limiter)
+	ethListener.Latency = latency
 	err = ethListener.Connect()
 	if err != nil {
-		log.Fatalf("Failed to connect to WebSocket: %v", err)
+		return Result{}, fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
+	defer ethListener.Close()
 
 	// Subscribe new heads
 	err = ethListener.SubscribeNewHeads()
 	if err != nil {
-		log.Fatalf("Failed to subscribe to new heads: %v", err)
+		return Result{}, fmt.Errorf("failed to subscribe to new heads: %w", err)
 	}
 
 	transmitter, err := NewTransmitter(httpRpc, limiter)
 	if err != nil {
-		log.Fatalf("Failed to create transmitter: %v", err)
+		return Result{}, fmt.Errorf("failed to create transmitter: %w", err)
+	}
+	if configure != nil {
+		configure(transmitter)
+	}
+	transmitter.Stats.SetSampling(total, options.GasReportSamples)
+	transmitter.Latency = latency
+	transmitter.BatchSize = options.BatchSize
+	transmitter.RpcUrls = options.RpcUrls
+	transmitter.RpcStrategy = options.RpcStrategy
+	transmitter.Workers = options.Workers
+	transmitter.Connections = options.Connections
+	transmitter.ErrorPolicies = errorPolicies
+	transmitter.MaxRetries = options.MaxRetries
+	transmitter.RetryBackoff = options.RetryBackoff
+	transmitter.FillNonceGaps = options.FillNonceGaps
+	if len(options.RpcUrls) > 1 {
+		fmt.Printf("Distributing txs over %d endpoints (%s)\n", len(options.RpcUrls), options.RpcStrategy)
+	}
+	if options.TargetTPS > 0 {
+		transmitter.Pacer = limiterpkg.NewTokenBucket(options.TargetTPS, burstOf(options.TargetTPS))
+		fmt.Printf("Sending open-loop at %.0f tx/s\n", options.TargetTPS)
+	}
+	if profile != nil {
+		transmitter.Pacer = limiterpkg.NewTokenBucket(0, 1)
 	}
 
-	err = transmitter.Broadcast(txsMap)
+	start := time.Now()
+	done := make(chan struct{})
+	if profile != nil {
+		go profile.drive(transmitter.Pacer, start, done)
+	}
+	err = transmitter.BroadcastStreams(streams)
+	close(done)
 	if err != nil {
-		log.Fatalf("Failed to broadcast transactions: %v", err)
+		return Result{}, fmt.Errorf("%w: %w", ErrBroadcast, err)
+	}
+	elapsed := time.Since(start)
+	if streamErr != nil {
+		err = <-streamErr
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to generate transactions: %w", err)
+		}
 	}
 
 	<-ethListener.quit
+
+	fmt.Println(transmitter.Stats.String())
+
+	result := ethListener.Result()
+	result.OfferedTPS = float64(transmitter.Stats.Sent) / elapsed.Seconds()
+	result.IncludedTPS = latency.IncludedRate(start)
+	result.Errors = transmitter.Stats.ErrorCounts()
+	result.Latency = latency.Summary()
+	fmt.Printf("Offered load: %.0f tx/s over %v, included at %.0f tx/s\n", result.OfferedTPS, elapsed.Round(time.Millisecond), result.IncludedTPS)
+	fmt.Println(result.Latency)
+	if profile != nil {
+		result.Phases = profile.results(latency, start, start.Add(elapsed))
+		fmt.Println(FormatPhaseResults(result.Phases))
+	}
+
+	err = ReportGasUsage(httpRpc, transmitter.Stats.Samples)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to report gas usage: %w", err)
+	}
+
+	return result, nil
+}
+
+// drainStreams reads the rest of every stream, only so that their producers
+// finish.
+func drainStreams(streams []<-chan *types.Transaction) {
+	var wg sync.WaitGroup
+	for _, txs := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range txs {
+			}
+		}()
+	}
+	wg.Wait()
 }
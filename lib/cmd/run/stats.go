@@ -0,0 +1,123 @@
+package run
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// TransmitStats counts the txs sent by a Transmitter and the fees they paid.
+// For legacy and access-list txs the fee cap and the tip are the gas price.
+// Every SampleEvery-th sent tx is kept in Samples for the gas report.
+type TransmitStats struct {
+	mutex    sync.Mutex
+	Sent     int64
+	Unsent   int64
+	FeeBumps int64
+	// GapFills counts the txs sent to close nonce gaps.
+	GapFills    int64
+	MinFeeCap   *big.Int
+	MaxFeeCap   *big.Int
+	MaxTipCap   *big.Int
+	SampleEvery int64
+	Samples     types.Transactions
+	// Errors counts the errors of sending txs by their class, including
+	// those dealt with by retrying or skipping.
+	Errors map[util.ErrorClass]int64
+}
+
+// SetSampling keeps about samples of total txs to be sent.
+func (s *TransmitStats) SetSampling(total, samples int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.SampleEvery = 0
+	if samples > 0 {
+		s.SampleEvery = int64(max(1, total/samples))
+	}
+}
+
+func (s *TransmitStats) addSent(tx *types.Transaction) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.SampleEvery > 0 && s.Sent%s.SampleEvery == 0 {
+		s.Samples = append(s.Samples, tx)
+	}
+	s.Sent++
+	if s.MinFeeCap == nil || tx.GasFeeCap().Cmp(s.MinFeeCap) < 0 {
+		s.MinFeeCap = tx.GasFeeCap()
+	}
+	if s.MaxFeeCap == nil || tx.GasFeeCap().Cmp(s.MaxFeeCap) > 0 {
+		s.MaxFeeCap = tx.GasFeeCap()
+	}
+	if s.MaxTipCap == nil || tx.GasTipCap().Cmp(s.MaxTipCap) > 0 {
+		s.MaxTipCap = tx.GasTipCap()
+	}
+}
+
+func (s *TransmitStats) addUnsent(count int64) {
+	s.mutex.Lock()
+	s.Unsent += count
+	s.mutex.Unlock()
+}
+
+func (s *TransmitStats) addError(class util.ErrorClass) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.Errors == nil {
+		s.Errors = make(map[util.ErrorClass]int64)
+	}
+	s.Errors[class]++
+}
+
+// ErrorCounts returns a copy of the error counts.
+func (s *TransmitStats) ErrorCounts() map[util.ErrorClass]int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[util.ErrorClass]int64, len(s.Errors))
+	for class, count := range s.Errors {
+		counts[class] = count
+	}
+	return counts
+}
+
+func (s *TransmitStats) addGapFill() {
+	s.mutex.Lock()
+	s.GapFills++
+	s.mutex.Unlock()
+}
+
+func (s *TransmitStats) addFeeBump() {
+	s.mutex.Lock()
+	s.FeeBumps++
+	s.mutex.Unlock()
+}
+
+func (s *TransmitStats) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	str := fmt.Sprintf("Sent: %d FeeCap: %v-%v MaxTip: %v FeeBumps: %d", s.Sent, s.MinFeeCap, s.MaxFeeCap, s.MaxTipCap, s.FeeBumps)
+	if s.Unsent > 0 {
+		str += fmt.Sprintf(" Unsent: %d", s.Unsent)
+	}
+	if s.GapFills > 0 {
+		str += fmt.Sprintf(" GapFills: %d", s.GapFills)
+	}
+	if len(s.Errors) > 0 {
+		str += " Errors:"
+		for _, class := range util.ErrorClasses {
+			if count := s.Errors[class]; count > 0 {
+				str += fmt.Sprintf(" %s=%d", class, count)
+			}
+		}
+	}
+	return str
+}
@@ -2,17 +2,67 @@ package run
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	limiterpkg "github.com/0glabs/evmchainbench/lib/limiter"
+	"github.com/0glabs/evmchainbench/lib/store"
+	"github.com/0glabs/evmchainbench/lib/util"
 )
 
 type Transmitter struct {
 	RpcUrl  string
 	limiter *limiterpkg.RateLimiter
+	// Resigner signs a tx rejected as underpriced again with bumped fees.
+	// It is nil when the private keys are unknown, e.g. for loaded txs.
+	Resigner    func(tx *types.Transaction) (*types.Transaction, error)
+	MaxFeeBumps int
+	Stats       TransmitStats
+	// CheckpointStore, if not nil, receives the progress of every stream
+	// every CheckpointInterval, so an interrupted broadcast can be resumed.
+	CheckpointStore    *store.Store
+	CheckpointInterval time.Duration
+	// Skipped are the numbers of txs left out at the start of every stream,
+	// so that checkpoints hold indexes in the tx files. Offsets, if not nil,
+	// are the offsets in the tx files the streams start at, so that
+	// checkpoints hold the offsets to resume at.
+	Skipped []int
+	Offsets []int64
+	// Pacer, if not nil, schedules every tx instead of the mempool limiter.
+	// The offered load then stays constant whatever the chain includes.
+	Pacer *limiterpkg.TokenBucket
+	// Latency, if not nil, records when every tx was sent.
+	Latency *LatencyTracker
+	// BatchSize, if above 1, is the number of txs of a stream sent in one
+	// JSON-RPC batch.
+	BatchSize int
+	// RpcUrls, if not empty, are the endpoints txs are distributed over by
+	// RpcStrategy instead of RpcUrl.
+	RpcUrls     []string
+	RpcStrategy string
+	// Workers is the number of txs in flight at once, one per stream if not
+	// positive. Connections limits the HTTP connections per endpoint, one
+	// per worker if not positive.
+	Workers     int
+	Connections int
+	// ErrorPolicies deal with the errors of sending txs by their class,
+	// DefaultErrorPolicies apply to the classes missing. Retries back off
+	// from RetryBackoff, MaxRetries times at most.
+	ErrorPolicies map[util.ErrorClass]ErrorPolicy
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	// FillNonceGaps makes the Transmitter check the pending nonces of the
+	// senders every NonceGapInterval and send the txs of a stuck sender
+	// again. Nonces it skipped are filled by NonceFiller, if not nil.
+	FillNonceGaps    bool
+	NonceGapInterval time.Duration
+	NonceFiller      func(sender common.Address, nonce uint64) (*types.Transaction, error)
 }
 
 func NewTransmitter(rpcUrl string, limiter *limiterpkg.RateLimiter) (*Transmitter, error) {
@@ -23,44 +73,291 @@ func NewTransmitter(rpcUrl string, limiter *limiterpkg.RateLimiter) (*Transmitte
 }
 
 func (t *Transmitter) Broadcast(txsMap map[int]types.Transactions) error {
-	ch := make(chan error)
+	return t.BroadcastStreams(streamsOf(txsMap))
+}
 
-	for _, txs := range txsMap {
-		go func(txs []*types.Transaction) {
-			client, err := ethclient.Dial(t.RpcUrl)
-			if err != nil {
-				ch <- err
-				return
-			}
+// BroadcastStreams sends the txs of every stream in order. Streams usually
+// hold the txs of one sender each. A pool of Workers takes turns on the
+// streams, and a stream is only ever served by one worker at a time, so the
+// txs of every sender keep their nonce order however many workers there are.
+func (t *Transmitter) BroadcastStreams(streams []<-chan *types.Transaction) error {
+	progress := newProgress(len(streams), t.Skipped, t.Offsets)
+	done := make(chan struct{})
+	checkpointErr := make(chan error, 1)
+	if t.CheckpointStore != nil {
+		go func() {
+			checkpointErr <- t.checkpointPeriodically(progress, done)
+		}()
+	} else {
+		checkpointErr <- nil
+	}
+
+	err := t.broadcastStreams(streams, progress)
+	close(done)
+	if err != nil {
+		<-checkpointErr
+		return err
+	}
+
+	return <-checkpointErr
+}
+
+func (t *Transmitter) broadcastStreams(streams []<-chan *types.Transaction, progress *progress) error {
+	if len(streams) == 0 {
+		return nil
+	}
+
+	urls := t.RpcUrls
+	if len(urls) == 0 {
+		urls = []string{t.RpcUrl}
+	}
+	workers := t.Workers
+	if workers <= 0 {
+		workers = len(streams)
+	}
+	connections := t.Connections
+	if connections <= 0 {
+		connections = workers
+	}
+	endpoints, err := dialEndpoints(urls, t.RpcStrategy, connections)
+	if err != nil {
+		return err
+	}
+	defer endpoints.Close()
+
+	if t.FillNonceGaps {
+		progress.gaps = newNonceGaps(len(streams))
+		done, stopped := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			t.fillNonceGapsPeriodically(progress.gaps, endpoints, done)
+		}()
+		defer func() {
+			close(done)
+			<-stopped
+		}()
+	}
+
+	// the streams waiting for a worker to take a turn on them
+	ready := make(chan int, len(streams))
+	for index := range streams {
+		ready <- index
+	}
+	var remaining atomic.Int64
+	remaining.Store(int64(len(streams)))
 
-			for _, tx := range txs {
-				for {
-					if t.limiter == nil || t.limiter.AllowRequest() {
-						err := broadcast(client, tx)
-						if err != nil {
-							ch <- err
-							return
-						}
-						break
-					} else {
-						time.Sleep(10 * time.Millisecond)
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				var index int
+				var ok bool
+				select {
+				case index, ok = <-ready:
+					if !ok {
+						return
 					}
+				case <-failed:
+					return
+				}
 
+				finished, err := t.takeTurn(endpoints.client(index), index, streams[index], progress)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+					return
+				}
+				if !finished {
+					ready <- index
+				} else if remaining.Add(-1) == 0 {
+					close(ready)
 				}
 			}
-			ch <- nil
-		}(txs)
+		}()
 	}
+	wg.Wait()
 
-	senderCount := len(txsMap)
-	for i := 0; i < senderCount; i++ {
-		err := <-ch
+	return firstErr
+}
+
+// takeTurn sends the next tx of the stream with the given index, or the next
+// batch if batching. It returns whether the stream is finished.
+func (t *Transmitter) takeTurn(client *ethclient.Client, index int, txs <-chan *types.Transaction, progress *progress) (bool, error) {
+	tx, ok := <-txs
+	if !ok {
+		return true, nil
+	}
+	if !t.wait() {
+		t.drain(txs, 1)
+		return true, nil
+	}
+
+	if t.BatchSize <= 1 {
+		sent, err := t.send(client, tx)
 		if err != nil {
-			return err
+			return false, err
 		}
+		progress.sent(index, tx, sent)
+		return false, nil
 	}
 
-	return nil
+	// a batch is sent once full or when no tx is waiting, so batching never
+	// holds txs back
+	batch := types.Transactions{tx}
+	finished := false
+fill:
+	for len(batch) < t.BatchSize {
+		select {
+		case tx, ok := <-txs:
+			if !ok {
+				finished = true
+				break fill
+			}
+			if !t.wait() {
+				t.drain(txs, 1)
+				finished = true
+				break fill
+			}
+			batch = append(batch, tx)
+		default:
+			break fill
+		}
+	}
+
+	err := t.sendBatch(client, batch, func(tx, sent *types.Transaction) {
+		progress.sent(index, tx, sent)
+	})
+	if err != nil {
+		return false, err
+	}
+	return finished, nil
+}
+
+// drain reads the rest of txs once the load profile ended, only so that the
+// producer of the stream finishes, and counts them with the unsent ones
+// already taken.
+func (t *Transmitter) drain(txs <-chan *types.Transaction, taken int64) {
+	unsent := taken
+	for range txs {
+		unsent++
+	}
+	t.Stats.addUnsent(unsent)
+}
+
+// streamsOf turns the txs of every sender into a stream ready to be read.
+func streamsOf(txsMap map[int]types.Transactions) []<-chan *types.Transaction {
+	streams := make([]<-chan *types.Transaction, len(txsMap))
+	for index := range streams {
+		txs := txsMap[index]
+		stream := make(chan *types.Transaction, len(txs))
+		for _, tx := range txs {
+			stream <- tx
+		}
+		close(stream)
+		streams[index] = stream
+	}
+	return streams
+}
+
+// wait blocks until the next tx may be sent: on the schedule of the pacer in
+// open-loop mode, otherwise until the mempool limiter has room. It returns
+// false once the pacer is stopped.
+func (t *Transmitter) wait() bool {
+	if t.Pacer != nil {
+		return t.Pacer.Take()
+	}
+
+	for t.limiter != nil && !t.limiter.AllowRequest() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// send broadcasts tx, re-signing it with bumped fees while it is rejected as
+// underpriced. It returns the tx that went out, see settle.
+func (t *Transmitter) send(client *ethclient.Client, tx *types.Transaction) (*types.Transaction, error) {
+	sentAt := time.Now()
+	return t.settle(client, tx, sentAt, broadcast(client, tx))
+}
+
+// settle handles the outcome err of broadcasting tx at sentAt. While tx is
+// rejected as underpriced, it is re-signed with bumped fees and sent again.
+// Any other error is counted by its class and dealt with by the policy of
+// the class. A tx the node already knows counts as sent when it was first
+// broadcast. It returns the tx that went out, which is a re-signed one after
+// fee bumps, or nil if tx was dropped.
+func (t *Transmitter) settle(client *ethclient.Client, tx *types.Transaction, sentAt time.Time, err error) (*types.Transaction, error) {
+	// firstSentAt is when tx was first broadcast, retries may find an earlier
+	// attempt got through
+	firstSentAt := sentAt
+	bumps, retries := 0, 0
+	for err != nil {
+		class := util.ClassifyError(err)
+		t.Stats.addError(class)
+
+		if class == util.UnderpricedClass && t.Resigner != nil && bumps < t.MaxFeeBumps {
+			tx, err = t.Resigner(tx)
+			if err != nil {
+				return nil, err
+			}
+			bumps++
+			t.Stats.addFeeBump()
+			sentAt = time.Now()
+			firstSentAt = sentAt
+			err = broadcast(client, tx)
+			continue
+		}
+
+		switch t.errorPolicy(class) {
+		case SkipPolicy:
+			if class != util.AlreadyKnownClass {
+				return nil, nil
+			}
+			sentAt, err = firstSentAt, nil
+		case ResyncPolicy:
+			used, resyncErr := nonceUsed(client, tx)
+			if resyncErr != nil {
+				return nil, fmt.Errorf("failed to resync nonce after %w: %w", err, resyncErr)
+			}
+			if used {
+				// the nonce went to tx itself or to another tx replacing it
+				known, resyncErr := txKnown(client, tx)
+				if resyncErr != nil {
+					return nil, fmt.Errorf("failed to resync nonce after %w: %w", err, resyncErr)
+				}
+				if !known {
+					return nil, nil
+				}
+				sentAt, err = firstSentAt, nil
+				continue
+			}
+			fallthrough
+		case RetryPolicy:
+			if !t.retry(retries) {
+				return nil, fmt.Errorf("gave up after %d retries: %w", retries, err)
+			}
+			retries++
+			sentAt = time.Now()
+			err = broadcast(client, tx)
+		default:
+			return nil, err
+		}
+	}
+
+	t.Stats.addSent(tx)
+	if t.Latency != nil {
+		t.Latency.Sent(tx.Hash(), sentAt)
+	}
+	return tx, nil
 }
 
 func broadcast(client *ethclient.Client, tx *types.Transaction) error {
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/cmd/run"
+	"github.com/0glabs/evmchainbench/lib/generator"
+)
+
+// The chain is idle once idleBlocks empty blocks follow each other, which is
+// also how the listener tells a run is over. It is polled every idlePoll, for
+// maxIdleWait at most.
+const (
+	idleBlocks  = 3
+	idlePoll    = time.Second
+	maxIdleWait = 5 * time.Minute
+)
+
+// Options bound the search and define when a level is sustainable.
+type Options struct {
+	MinTPS float64
+	MaxTPS float64
+	// Precision is the width of the range the search stops at.
+	Precision float64
+	// Window is how long every level is offered.
+	Window time.Duration
+	// LatencySLO is the highest p95 inclusion latency of a sustainable level.
+	LatencySLO time.Duration
+	// MinInclusion is the lowest ratio of the included to the offered rate
+	// of a sustainable level.
+	MinInclusion float64
+}
+
+// Level is the outcome of offering one rate.
+type Level struct {
+	TPS       float64
+	Result    run.Result
+	Reason    string
+	Sustained bool
+}
+
+// Search binary-searches the highest rate the chain sustains. Every level is
+// a fresh open-loop run of Window at that rate; it is sustained if inclusion
+// keeps up with the offered rate and the p95 inclusion latency stays within
+// the SLO. Levels failing to broadcast are not sustained either, any other
+// error stops the search. Before every level but the first, the chain works
+// off the txs left over from the previous one.
+func Search(httpRpc, wsRpc, faucetPrivateKey string, senderCount int, txType string, searchOptions Options, options run.Options, generatorOptions generator.Options) (float64, error) {
+	if searchOptions.MinTPS <= 0 || searchOptions.MaxTPS <= searchOptions.MinTPS {
+		return 0, fmt.Errorf("the TPS range [%v, %v] is not valid", searchOptions.MinTPS, searchOptions.MaxTPS)
+	}
+	if options.LoadProfile != "" {
+		return 0, fmt.Errorf("a load profile cannot be searched")
+	}
+
+	var levels []Level
+	try := func(tps float64) (bool, error) {
+		if len(levels) > 0 {
+			err := waitIdle(httpRpc)
+			if err != nil {
+				return false, fmt.Errorf("failed to wait for the chain to go idle: %w", err)
+			}
+		}
+
+		fmt.Printf("Offering %.0f tx/s for %v\n", tps, searchOptions.Window)
+		level, err := searchOptions.try(httpRpc, wsRpc, faucetPrivateKey, senderCount, txType, tps, options, generatorOptions)
+		if err != nil {
+			return false, fmt.Errorf("%.0f tx/s: %w", tps, err)
+		}
+		levels = append(levels, level)
+		if level.Sustained {
+			fmt.Printf("%.0f tx/s is sustained\n", tps)
+		} else {
+			fmt.Printf("%.0f tx/s is not sustained: %s\n", tps, level.Reason)
+		}
+		return level.Sustained, nil
+	}
+
+	var best float64
+	low, high := searchOptions.MinTPS, searchOptions.MaxTPS
+	sustained, err := try(low)
+	if err != nil {
+		return 0, err
+	}
+	if sustained {
+		best = low
+		for high-low > searchOptions.Precision {
+			mid := math.Round((low + high) / 2)
+			if mid <= low || mid >= high {
+				break
+			}
+			sustained, err = try(mid)
+			if err != nil {
+				return 0, err
+			}
+			if sustained {
+				best, low = mid, mid
+			} else {
+				high = mid
+			}
+		}
+	}
+
+	fmt.Println("      TPS  Included TPS      p95  Sustained")
+	for _, level := range levels {
+		fmt.Printf("%9.0f %13.0f %8v  %v\n", level.TPS, level.Result.IncludedTPS, level.Result.Latency.P95.Round(time.Millisecond), level.Sustained)
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("not even %.0f tx/s is sustained", searchOptions.MinTPS)
+	}
+	fmt.Printf("Max sustainable TPS: %.0f (p95 SLO %v)\n", best, searchOptions.LatencySLO)
+
+	return best, nil
+}
+
+// try offers tps open-loop for the window and judges the result.
+func (so Options) try(httpRpc, wsRpc, faucetPrivateKey string, senderCount int, txType string, tps float64, options run.Options, generatorOptions generator.Options) (Level, error) {
+	level := Level{TPS: tps}
+
+	options.TargetTPS = tps
+	txCount := int(math.Ceil(tps * so.Window.Seconds() / float64(senderCount)))
+	result, err := run.Benchmark(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, options, generatorOptions)
+	if errors.Is(err, run.ErrBroadcast) {
+		level.Reason = err.Error()
+		return level, nil
+	}
+	if err != nil {
+		return level, err
+	}
+	level.Result = result
+
+	switch {
+	case result.IncludedTPS < tps*so.MinInclusion:
+		level.Reason = fmt.Sprintf("included %.0f tx/s", result.IncludedTPS)
+	case result.Latency.P95 > so.LatencySLO:
+		level.Reason = fmt.Sprintf("p95 latency %v", result.Latency.P95.Round(time.Millisecond))
+	default:
+		level.Sustained = true
+	}
+	return level, nil
+}
+
+// waitIdle blocks until the chain is idle, or gives up after maxIdleWait so
+// that busy chains can still be searched.
+func waitIdle(httpRpc string) error {
+	client, err := ethclient.Dial(httpRpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	deadline := time.Now().Add(maxIdleWait)
+	var last uint64
+	empty := 0
+	for empty < idleBlocks {
+		if time.Now().After(deadline) {
+			fmt.Printf("The chain is not idle after %v, offering the next level anyway\n", maxIdleWait)
+			return nil
+		}
+
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		number := header.Number.Uint64()
+		if number != last {
+			last = number
+			count, err := client.TransactionCount(ctx, header.Hash())
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				empty++
+			} else {
+				empty = 0
+			}
+			continue
+		}
+		time.Sleep(idlePoll)
+	}
+	return nil
+}
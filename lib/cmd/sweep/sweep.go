@@ -0,0 +1,41 @@
+package sweep
+
+import (
+	"fmt"
+
+	"github.com/0glabs/evmchainbench/lib/cmd/run"
+	"github.com/0glabs/evmchainbench/lib/generator"
+)
+
+// Sweep runs the same workload once per gas limit multiplier. On chains that
+// build blocks by gas limit and enforce a minimum gas used ratio, padding gas
+// limits directly costs TPS, so the table shows which multiplier is optimal.
+// Only estimated gas limits are multiplied, so simple transfers and the fixed
+// gas mode cannot be swept.
+func Sweep(httpRpc, wsRpc, faucetPrivateKey string, senderCount, txCount int, txType string, multipliers []float64, options run.Options, generatorOptions generator.Options) error {
+	if txType == "simple" {
+		return fmt.Errorf("simple transfers have a fixed gas limit, there is nothing to sweep")
+	}
+	if generatorOptions.GasMode == generator.FixedGasMode {
+		return fmt.Errorf("the %s gas mode ignores gas limit multipliers, there is nothing to sweep", generator.FixedGasMode)
+	}
+
+	results := make([]run.Result, len(multipliers))
+	for i, multiplier := range multipliers {
+		fmt.Printf("Gas limit multiplier: %.2f\n", multiplier)
+
+		generatorOptions.GasFactor = multiplier
+		result, err := run.Benchmark(httpRpc, wsRpc, faucetPrivateKey, senderCount, txCount, txType, options, generatorOptions)
+		if err != nil {
+			return fmt.Errorf("multiplier %.2f: %w", multiplier, err)
+		}
+		results[i] = result
+	}
+
+	fmt.Println("Multiplier  TPS  GasUsed%")
+	for i, result := range results {
+		fmt.Printf("%10.2f %4d %8.2f%%\n", multipliers[i], result.BestTPS, result.GasUsedAtBestTPS*100)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package sweep
+
+import (
+	"testing"
+
+	"github.com/0glabs/evmchainbench/lib/cmd/run"
+	"github.com/0glabs/evmchainbench/lib/generator"
+)
+
+func TestSweepRefusesFixedGas(t *testing.T) {
+	tests := []struct {
+		name    string
+		txType  string
+		gasMode string
+	}{
+		{"simple transfers", "simple", ""},
+		{"fixed gas mode", "erc20", generator.FixedGasMode},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the refusal comes before anything is dialed
+			err := Sweep("", "", "", 1, 1, test.txType, []float64{1, 1.5}, run.Options{}, generator.Options{GasMode: test.gasMode})
+			if err == nil {
+				t.Fatal("sweep was not refused")
+			}
+		})
+	}
+}
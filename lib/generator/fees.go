@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/account"
+)
+
+// FeeStrategy derives the fees of dynamic-fee txs from eth_feeHistory: the
+// tip is the given reward percentile averaged over the recent blocks, and the
+// fee cap adds Headroom times the next base fee on top of the base fee and
+// the tip, so that txs stay includable while the base fee rises. Legacy and
+// access-list txs use the node's gas price suggestion.
+type FeeStrategy struct {
+	HistoryBlocks uint64
+	Percentile    float64
+	Headroom      float64
+	// BumpPercent is how much fees are raised when a tx is underpriced. Nodes
+	// only accept a replacement tx that outbids the old one by at least 10%.
+	BumpPercent int64
+	// MaxBumps is how often an underpriced tx is re-signed before giving up.
+	MaxBumps int
+}
+
+func DefaultFeeStrategy() FeeStrategy {
+	return FeeStrategy{
+		HistoryBlocks: 20,
+		Percentile:    50,
+		Headroom:      1,
+		BumpPercent:   15,
+		MaxBumps:      3,
+	}
+}
+
+// suggestFees picks the tx envelope and the fees of generated txs. Unless set
+// in options, the envelope is dynamic-fee on chains with a base fee and legacy
+// otherwise.
+func suggestFees(client *ethclient.Client, header *types.Header, options Options) (TxFees, error) {
+	txType := options.TxType
+	if txType == "" {
+		txType = LegacyTxType
+		if header.BaseFee != nil {
+			txType = DynamicFeeTxType
+		}
+	}
+
+	switch txType {
+	case LegacyTxType, AccessListTxType:
+		gasPrice, err := client.SuggestGasPrice(context.Background())
+		if err != nil {
+			return TxFees{}, err
+		}
+		return TxFees{TxType: txType, GasPrice: gasPrice}, nil
+	case DynamicFeeTxType:
+		if header.BaseFee == nil {
+			return TxFees{}, fmt.Errorf("chain does not support EIP-1559, %v txs cannot be used", txType)
+		}
+
+		baseFee, tip, err := options.FeeStrategy.feeHistory(client, header)
+		if err != nil {
+			return TxFees{}, err
+		}
+		if options.GasTipCap != nil {
+			tip = options.GasTipCap
+		}
+
+		feeCap := options.GasFeeCap
+		if feeCap == nil {
+			headroom := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(options.FeeStrategy.Headroom))
+			feeCap, _ = headroom.Int(nil)
+			feeCap.Add(feeCap, baseFee)
+			feeCap.Add(feeCap, tip)
+		}
+		if feeCap.Cmp(tip) < 0 {
+			return TxFees{}, fmt.Errorf("gas fee cap %v is lower than gas tip cap %v", feeCap, tip)
+		}
+
+		return TxFees{TxType: txType, GasTipCap: tip, GasFeeCap: feeCap}, nil
+	default:
+		return TxFees{}, fmt.Errorf("tx envelope \"%v\" is not valid", txType)
+	}
+}
+
+// feeHistory returns the base fee of the next block and the average tip paid
+// at the configured percentile. Chains without eth_feeHistory fall back to the
+// base fee of header and the node's tip suggestion.
+func (s FeeStrategy) feeHistory(client *ethclient.Client, header *types.Header) (*big.Int, *big.Int, error) {
+	history, err := client.FeeHistory(context.Background(), s.HistoryBlocks, nil, []float64{s.Percentile})
+	if err != nil || len(history.BaseFee) == 0 {
+		fmt.Println("eth_feeHistory is not available, using suggested tip:", err)
+		tip, err := client.SuggestGasTipCap(context.Background())
+		if err != nil {
+			return nil, nil, err
+		}
+		return header.BaseFee, tip, nil
+	}
+
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	sum := big.NewInt(0)
+	count := int64(0)
+	for _, rewards := range history.Reward {
+		// empty blocks report a zero reward which says nothing about the tip
+		if len(rewards) == 0 || rewards[0].Sign() == 0 {
+			continue
+		}
+		sum.Add(sum, rewards[0])
+		count++
+	}
+	if count == 0 {
+		tip, err := client.SuggestGasTipCap(context.Background())
+		if err != nil {
+			return nil, nil, err
+		}
+		return baseFee, tip, nil
+	}
+
+	return baseFee, sum.Div(sum, big.NewInt(count)), nil
+}
+
+// Bump raises every fee by BumpPercent, rounding up so that small fees still
+// grow.
+func (s FeeStrategy) Bump(fees TxFees) TxFees {
+	bump := func(fee *big.Int) *big.Int {
+		if fee == nil {
+			return nil
+		}
+		bumped := new(big.Int).Mul(fee, big.NewInt(100+s.BumpPercent))
+		bumped.Add(bumped, big.NewInt(99))
+		return bumped.Div(bumped, big.NewInt(100))
+	}
+
+	return TxFees{
+		TxType:    fees.TxType,
+		GasPrice:  bump(fees.GasPrice),
+		GasTipCap: bump(fees.GasTipCap),
+		GasFeeCap: bump(fees.GasFeeCap),
+	}
+}
+
+// feesOf returns the fees paid by tx.
+func feesOf(tx *types.Transaction) TxFees {
+	switch tx.Type() {
+	case types.DynamicFeeTxType:
+		return TxFees{TxType: DynamicFeeTxType, GasTipCap: tx.GasTipCap(), GasFeeCap: tx.GasFeeCap()}
+	case types.AccessListTxType:
+		return TxFees{TxType: AccessListTxType, GasPrice: tx.GasPrice()}
+	default:
+		return TxFees{TxType: LegacyTxType, GasPrice: tx.GasPrice()}
+	}
+}
+
+// Resign signs tx again with its fees bumped by the fee strategy, so that it
+// replaces the original tx rejected as underpriced.
+func (g *Generator) Resign(tx *types.Transaction) (*types.Transaction, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(g.ChainID), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range append([]*account.Account{g.FaucetAccount}, g.Senders...) {
+		if acc.Address == from {
+			fees := g.FeeStrategy.Bump(feesOf(tx))
+			return signTx(acc.PrivateKey, g.ChainID, fees, tx.Nonce(), tx.To(), tx.Value(), tx.Gas(), tx.Data())
+		}
+	}
+
+	return nil, fmt.Errorf("no private key of sender %s", from.Hex())
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Gas modes decide the gas limit of the contract calls of a workload:
+//   - fixed uses the constant of the workload in gas_limit.go
+//   - estimate estimates a sample tx once and applies the result to every tx
+//   - per-tx calls eth_estimateGas for every single tx
+//
+// Estimated gas is multiplied by the gas factor of the generator, or by the
+// default factor of the workload when it is not set.
+const (
+	FixedGasMode    = "fixed"
+	EstimateGasMode = "estimate"
+	PerTxGasMode    = "per-tx"
+)
+
+// gasPlan is the gas limit decided for the txs of a workload. In per-tx mode
+// the limit only serves as the cap of each estimation.
+type gasPlan struct {
+	mode   string
+	factor float64
+	limit  uint64
+}
+
+// planGas decides the gas limit of the workload txs. sample is a signed
+// workload tx using the fixed gas limit, it is only estimated in estimate
+// mode.
+func (g *Generator) planGas(fixedLimit uint64, defaultFactor float64, sample *types.Transaction) (gasPlan, error) {
+	factor := g.GasFactor
+	if factor == 0 {
+		factor = defaultFactor
+	}
+
+	plan := gasPlan{mode: g.GasMode, factor: factor, limit: fixedLimit}
+	switch g.GasMode {
+	case FixedGasMode, PerTxGasMode:
+	case EstimateGasMode:
+		from, err := types.Sender(types.LatestSignerForChainID(g.ChainID), sample)
+		if err != nil {
+			return plan, err
+		}
+		gas, err := g.estimateGas(ConvertTxToCallMsg(sample, from))
+		if err != nil {
+			return plan, err
+		}
+		plan.limit = plan.apply(gas)
+	default:
+		return plan, fmt.Errorf("gas mode \"%v\" is not valid", g.GasMode)
+	}
+
+	fmt.Printf("Gas mode: %s factor: %.2f limit: %d\n", plan.mode, plan.factor, plan.limit)
+	return plan, nil
+}
+
+func (p gasPlan) apply(gas uint64) uint64 {
+	return uint64(p.factor * float64(gas))
+}
+
+// build signs a workload tx with the planned gas limit. In per-tx mode the tx
+// is estimated first and signed again with the estimated gas limit.
+func (p gasPlan) build(client *ethclient.Client, from common.Address, build func(gasLimit uint64) (*types.Transaction, error)) (*types.Transaction, error) {
+	tx, err := build(p.limit)
+	if err != nil || p.mode != PerTxGasMode {
+		return tx, err
+	}
+
+	gas, err := client.EstimateGas(context.Background(), ConvertTxToCallMsg(tx, from))
+	if err != nil {
+		return nil, err
+	}
+
+	return build(p.apply(gas))
+}
@@ -5,11 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -18,7 +16,6 @@ import (
 	"github.com/0glabs/evmchainbench/lib/account"
 	"github.com/0glabs/evmchainbench/lib/contract_meta_data/erc20"
 	"github.com/0glabs/evmchainbench/lib/store"
-	"github.com/0glabs/evmchainbench/lib/util"
 )
 
 type Generator struct {
@@ -27,49 +24,92 @@ type Generator struct {
 	Recipients    []string
 	RpcUrl        string
 	ChainID       *big.Int
-	GasPrice      *big.Int
+	Fees          TxFees
+	FeeStrategy   FeeStrategy
+	GasMode       string
+	GasFactor     float64
+	Workers       int
+	// SwapDeadline is how long the swaps of the Uniswap workload stay valid.
+	SwapDeadline time.Duration
+	// Deadline is the unix time after which the workload txs revert, 0 if
+	// they have none. It is set once the workload is prepared.
+	Deadline      int64
 	ShouldPersist bool
 	Store         *store.Store
-	EIP1559       bool
+	FillNonceGaps bool
 }
 
-func NewGenerator(rpcUrl, faucetPrivateKey string, senderCount, txCount int, shouldPersist bool, txStoreDir string) (*Generator, error) {
+// Options holds the optional knobs of a Generator.
+type Options struct {
+	// FillNonceGaps makes the prepare phase fill nonce gaps left by dropped
+	// txs instead of giving up when their receipts do not show up.
+	FillNonceGaps bool
+	// TxType is the envelope of generated txs, picked from the chain if empty.
+	TxType string
+	// GasTipCap and GasFeeCap override the fees of dynamic-fee txs.
+	GasTipCap   *big.Int
+	GasFeeCap   *big.Int
+	FeeStrategy FeeStrategy
+	// GasMode is one of the gas modes in gas.go, estimate if empty.
+	GasMode string
+	// GasFactor multiplies estimated gas, 0 uses the workload default.
+	GasFactor float64
+	// Workers is the number of goroutines signing txs, 0 for one per CPU.
+	Workers int
+	// SwapDeadline is how long the swaps of the Uniswap workload stay valid,
+	// 0 picks a default depending on whether the txs are persisted.
+	SwapDeadline time.Duration
+}
+
+func NewGenerator(rpcUrl, faucetPrivateKey string, senderCount, txCount int, shouldPersist bool, txStoreDir string, options Options) (*Generator, error) {
 	client, err := ethclient.Dial(rpcUrl)
 	if err != nil {
-		return &Generator{}, err
+		return &Generator{}, fmt.Errorf("failed to dial %s: %w", rpcUrl, err)
 	}
+	defer client.Close()
 
-	eip1559 := false
 	header, err := client.HeaderByNumber(context.Background(), nil)
 	if err != nil {
-		return &Generator{}, err
-	}
-	if header.BaseFee != nil {
-		eip1559 = true
+		return &Generator{}, fmt.Errorf("failed to get latest header: %w", err)
 	}
 
-	fmt.Println("EIP-1559:", eip1559)
+	fmt.Println("EIP-1559:", header.BaseFee != nil)
+
+	if options.FeeStrategy == (FeeStrategy{}) {
+		options.FeeStrategy = DefaultFeeStrategy()
+	}
+	if options.GasMode == "" {
+		options.GasMode = EstimateGasMode
+	}
+	if options.SwapDeadline == 0 {
+		options.SwapDeadline = defaultSwapDeadline
+		if shouldPersist {
+			options.SwapDeadline = persistedSwapDeadline
+		}
+	}
 
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	fees, err := suggestFees(client, header, options)
 	if err != nil {
-		return &Generator{}, err
+		return &Generator{}, fmt.Errorf("failed to suggest fees: %w", err)
 	}
 
+	fmt.Println("Fees:", fees)
+
 	chainID, err := client.NetworkID(context.Background())
 	if err != nil {
-		return &Generator{}, err
+		return &Generator{}, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
 	faucetAccount, err := account.CreateFaucetAccount(client, faucetPrivateKey)
 	if err != nil {
-		return &Generator{}, err
+		return &Generator{}, fmt.Errorf("failed to create faucet account: %w", err)
 	}
 
 	senders := make([]*account.Account, senderCount)
 	for i := 0; i < senderCount; i++ {
 		s, err := account.NewAccount(client)
 		if err != nil {
-			return &Generator{}, err
+			return &Generator{}, fmt.Errorf("failed to create sender %d: %w", i, err)
 		}
 		senders[i] = s
 	}
@@ -83,103 +123,102 @@ func NewGenerator(rpcUrl, faucetPrivateKey string, senderCount, txCount int, sho
 		recipients[i] = r
 	}
 
-	client.Close()
-
 	return &Generator{
 		FaucetAccount: faucetAccount,
 		Senders:       senders,
 		Recipients:    recipients,
 		RpcUrl:        rpcUrl,
 		ChainID:       chainID,
-		GasPrice:      gasPrice,
+		Fees:          fees,
+		FeeStrategy:   options.FeeStrategy,
+		GasMode:       options.GasMode,
+		GasFactor:     options.GasFactor,
+		Workers:       options.Workers,
+		SwapDeadline:  options.SwapDeadline,
 		ShouldPersist: shouldPersist,
 		Store:         store.NewStore(txStoreDir),
-		EIP1559:       eip1559,
+		FillNonceGaps: options.FillNonceGaps,
 	}, nil
 }
 
-func (g *Generator) approveERC20(token common.Address, spender common.Address) {
+func (g *Generator) approveERC20(token common.Address, spender common.Address) error {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer client.Close()
 	txs := types.Transactions{}
 	for _, sender := range append(g.Senders, g.FaucetAccount) {
-		tx := GenerateContractCallingTx(
-			sender.PrivateKey,
-			token.Hex(),
-			sender.GetNonce(),
-			g.ChainID,
-			g.GasPrice,
-			erc20TransferGasLimit,
-			erc20.MyTokenABI,
-			"approve",
-			spender,
-			big.NewInt(1000000000000000000),
-		)
-
-		err = client.SendTransaction(context.Background(), tx)
+		tx, err := g.sendTx(client, sender, func(nonce uint64) (*types.Transaction, error) {
+			return GenerateContractCallingTx(
+				sender.PrivateKey,
+				token.Hex(),
+				nonce,
+				g.ChainID,
+				g.Fees,
+				erc20TransferGasLimit,
+				erc20.MyTokenABI,
+				"approve",
+				spender,
+				big.NewInt(1000000000000000000),
+			)
+		})
 		if err != nil {
-			panic(err)
-		}
-
-		if g.ShouldPersist {
-			g.Store.AddPrepareTx(tx)
+			return fmt.Errorf("failed to approve %s for %s: %w", token.Hex(), spender.Hex(), err)
 		}
 
 		txs = append(txs, tx)
 	}
 
-	err = util.WaitForReceiptsOfTxs(client, txs, 20*time.Second)
+	err = g.waitForReceipts(client, txs)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to wait for approvals of %s: %w", token.Hex(), err)
 	}
+
+	return nil
 }
 
-func (g *Generator) prepareERC20(contractAddressStr string) {
+func (g *Generator) prepareERC20(contractAddressStr string) error {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer client.Close()
 	txs := types.Transactions{}
 	for _, sender := range g.Senders {
-		tx := GenerateContractCallingTx(
-			g.FaucetAccount.PrivateKey,
-			contractAddressStr,
-			g.FaucetAccount.GetNonce(),
-			g.ChainID,
-			g.GasPrice,
-			erc20TransferGasLimit,
-			erc20.MyTokenABI,
-			"transfer",
-			sender.Address,
-			big.NewInt(10000000),
-		)
-
-		err = client.SendTransaction(context.Background(), tx)
+		tx, err := g.sendTx(client, g.FaucetAccount, func(nonce uint64) (*types.Transaction, error) {
+			return GenerateContractCallingTx(
+				g.FaucetAccount.PrivateKey,
+				contractAddressStr,
+				nonce,
+				g.ChainID,
+				g.Fees,
+				erc20TransferGasLimit,
+				erc20.MyTokenABI,
+				"transfer",
+				sender.Address,
+				big.NewInt(10000000),
+			)
+		})
 		if err != nil {
-			panic(err)
-		}
-
-		if g.ShouldPersist {
-			g.Store.AddPrepareTx(tx)
+			return fmt.Errorf("failed to transfer %s to sender %s: %w", contractAddressStr, sender.Address.Hex(), err)
 		}
 
 		txs = append(txs, tx)
 	}
 
-	err = util.WaitForReceiptsOfTxs(client, txs, 20*time.Second)
+	err = g.waitForReceipts(client, txs)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to wait for transfers of %s: %w", contractAddressStr, err)
 	}
+
+	return nil
 }
 
-func (g *Generator) prepareSenders() {
+func (g *Generator) prepareSenders() error {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer client.Close()
 
@@ -189,41 +228,36 @@ func (g *Generator) prepareSenders() {
 	txs := types.Transactions{}
 
 	for _, recipient := range g.Senders {
-		signedTx, err := GenerateSimpleTransferTx(g.FaucetAccount.PrivateKey, recipient.Address.Hex(), g.FaucetAccount.GetNonce(), g.ChainID, g.GasPrice, value, g.EIP1559)
-		if err != nil {
-			panic(err)
-		}
-
-		err = client.SendTransaction(context.Background(), signedTx)
+		signedTx, err := g.sendTx(client, g.FaucetAccount, func(nonce uint64) (*types.Transaction, error) {
+			return GenerateSimpleTransferTx(g.FaucetAccount.PrivateKey, recipient.Address.Hex(), nonce, g.ChainID, g.Fees, value)
+		})
 		if err != nil {
-			panic(err)
-		}
-
-		if g.ShouldPersist {
-			g.Store.AddPrepareTx(signedTx)
+			return fmt.Errorf("failed to fund sender %s: %w", recipient.Address.Hex(), err)
 		}
 
 		txs = append(txs, signedTx)
 	}
 
-	err = util.WaitForReceiptsOfTxs(client, txs, 20*time.Second)
+	err = g.waitForReceipts(client, txs)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to wait for funding of senders: %w", err)
 	}
+
+	return nil
 }
 
-func (g *Generator) estimateGas(msg ethereum.CallMsg) uint64 {
+func (g *Generator) estimateGas(msg ethereum.CallMsg) (uint64, error) {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return 0, err
 	}
 	defer client.Close()
 
 	gas, err := client.EstimateGas(context.Background(), msg)
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("failed to estimate gas of call from %s: %w", msg.From.Hex(), err)
 	}
-	return gas
+	return gas, nil
 }
 
 func (g *Generator) deployContract(gasLimit uint64, contractBin, contractABI string, args ...interface{}) (common.Address, error) {
@@ -232,96 +266,82 @@ func (g *Generator) deployContract(gasLimit uint64, contractBin, contractABI str
 		return common.Address{}, err
 	}
 	defer client.Close()
-	tx, err := GenerateContractCreationTx(
-		g.FaucetAccount.PrivateKey,
-		g.FaucetAccount.GetNonce(),
-		g.ChainID,
-		g.GasPrice,
-		gasLimit,
-		contractBin,
-		contractABI,
-		args...,
-	)
-	if err != nil {
-		panic(err)
-	}
-
-	err = client.SendTransaction(context.Background(), tx)
+	tx, err := g.sendTx(client, g.FaucetAccount, func(nonce uint64) (*types.Transaction, error) {
+		return GenerateContractCreationTx(
+			g.FaucetAccount.PrivateKey,
+			nonce,
+			g.ChainID,
+			g.Fees,
+			gasLimit,
+			contractBin,
+			contractABI,
+			args...,
+		)
+	})
 	if err != nil {
-		panic(err)
+		return common.Address{}, fmt.Errorf("failed to deploy contract: %w", err)
 	}
 
 	ercContractAddress, err := bind.WaitDeployed(context.Background(), client, tx)
 	if err != nil {
-		fmt.Println("tx hash:", tx.Hash().Hex())
-		panic(err)
-	}
-
-	if g.ShouldPersist {
-		g.Store.AddPrepareTx(tx)
+		return common.Address{}, fmt.Errorf("failed to wait for deployment in tx %s: %w", tx.Hash().Hex(), err)
 	}
 
 	return ercContractAddress, nil
 }
 
-func (g *Generator) executeContractFunction(gasLimit uint64, contractAddress common.Address, contractABI, methodName string, args ...interface{}) {
+func (g *Generator) executeContractFunction(gasLimit uint64, contractAddress common.Address, contractABI, methodName string, args ...interface{}) error {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer client.Close()
 
-	tx := GenerateContractCallingTx(
-		g.FaucetAccount.PrivateKey,
-		contractAddress.Hex(),
-		g.FaucetAccount.GetNonce(),
-		g.ChainID,
-		g.GasPrice,
-		gasLimit,
-		contractABI,
-		methodName,
-		args...,
-	)
-
-	err = client.SendTransaction(context.Background(), tx)
+	tx, err := g.sendTx(client, g.FaucetAccount, func(nonce uint64) (*types.Transaction, error) {
+		return GenerateContractCallingTx(
+			g.FaucetAccount.PrivateKey,
+			contractAddress.Hex(),
+			nonce,
+			g.ChainID,
+			g.Fees,
+			gasLimit,
+			contractABI,
+			methodName,
+			args...,
+		)
+	})
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to call %s of %s: %w", methodName, contractAddress.Hex(), err)
 	}
 
 	receipt, err := bind.WaitMined(context.Background(), client, tx)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to wait for %s in tx %s: %w", methodName, tx.Hash().Hex(), err)
 	}
-	receiptJSON, err := json.MarshalIndent(receipt, "", "  ")
-
-	if err != nil {
-		panic(err)
-	}
-	if receipt.Status != 1 {
-		panic(string(receiptJSON))
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		receiptJSON, _ := json.MarshalIndent(receipt, "", "  ")
+		return fmt.Errorf("%s failed in tx %s: %s", methodName, tx.Hash().Hex(), receiptJSON)
 	}
 
-	if g.ShouldPersist {
-		g.Store.AddPrepareTx(tx)
-	}
+	return nil
 }
 
-func (g *Generator) callContractView(contractAddress common.Address, contractABI, methodName string, args ...interface{}) []interface{} {
+func (g *Generator) callContractView(contractAddress common.Address, contractABI, methodName string, args ...interface{}) ([]interface{}, error) {
 	client, err := ethclient.Dial(g.RpcUrl)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer client.Close()
 
 	// Parse the contract's ABI
-	parsedABI, err := abi.JSON(strings.NewReader(contractABI))
+	parsedABI, err := parseABI(contractABI)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	data, err := parsedABI.Pack(methodName, args...)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to pack call of %s: %w", methodName, err)
 	}
 
 	// Create a call message
@@ -333,13 +353,13 @@ func (g *Generator) callContractView(contractAddress common.Address, contractABI
 	// Send the call
 	result, err := client.CallContract(context.Background(), msg, nil)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to call %s of %s: %w", methodName, contractAddress.Hex(), err)
 	}
 
 	unpacked, err := parsedABI.Unpack(methodName, result)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to unpack result of %s: %w", methodName, err)
 	}
 
-	return unpacked
+	return unpacked, nil
 }
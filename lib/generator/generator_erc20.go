@@ -1,98 +1,80 @@
 package generator
 
 import (
-	"fmt"
 	"math/big"
-	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/0glabs/evmchainbench/lib/account"
 	"github.com/0glabs/evmchainbench/lib/contract_meta_data/erc20"
 )
 
 func (g *Generator) GenerateERC20() (map[int]types.Transactions, error) {
-	txsMap := make(map[int]types.Transactions)
+	return g.Generate("erc20")
+}
 
+// erc20Workload deploys an ERC20 token and hands it out to the senders, whose
+// txs then transfer small amounts of it to the recipients.
+func (g *Generator) erc20Workload() (build txBuilder, err error) {
 	if g.ShouldPersist {
-		defer g.Store.PersistPrepareTxs()
+		defer g.persistPrepareTxs(&err)
 	}
 
 	contractAddress, err := g.prepareContractERC20()
 	if err != nil {
-		return txsMap, err
+		return nil, err
 	}
 	contractAddressStr := contractAddress.Hex()
 
-	g.prepareSenders()
+	err = g.prepareSenders()
+	if err != nil {
+		return nil, err
+	}
 
-	g.prepareERC20(contractAddressStr)
+	err = g.prepareERC20(contractAddressStr)
+	if err != nil {
+		return nil, err
+	}
 
 	amount := big.NewInt(1000) // a random small amount
 
-	var mutex sync.Mutex
-	ch := make(chan error)
-
 	sender := g.Senders[0]
-	tx := GenerateContractCallingTx(
+	tx, err := GenerateContractCallingTx(
 		sender.PrivateKey,
 		contractAddressStr,
 		1,
 		g.ChainID,
-		g.GasPrice,
+		g.Fees,
 		erc20TransferGasLimit,
 		erc20.MyTokenABI,
 		"transfer",
 		common.HexToAddress(g.Recipients[0]),
 		amount,
 	)
-	ethCallTx := ConvertLegacyTxToCallMsg(tx, sender.Address)
-	estimateGas := g.estimateGas(ethCallTx)
-
-	fmt.Println("Estimated gas:", estimateGas)
-
-	for index, sender := range g.Senders {
-		go func(index int, sender *account.Account) {
-			txs := types.Transactions{}
-			for _, recipient := range g.Recipients {
-				tx := GenerateContractCallingTx(
-					sender.PrivateKey,
-					contractAddressStr,
-					sender.GetNonce(),
-					g.ChainID,
-					g.GasPrice,
-					estimateGas,
-					erc20.MyTokenABI,
-					"transfer",
-					common.HexToAddress(recipient),
-					amount,
-				)
-				txs = append(txs, tx)
-			}
-
-			mutex.Lock()
-			txsMap[index] = txs
-			mutex.Unlock()
-			ch <- nil
-		}(index, sender)
+	if err != nil {
+		return nil, err
 	}
-
-	for i := 0; i < len(g.Senders); i++ {
-		msg := <-ch
-		if msg != nil {
-			return txsMap, msg
-		}
+	plan, err := g.planGas(erc20TransferGasLimit, 1, tx)
+	if err != nil {
+		return nil, err
 	}
 
-	if g.ShouldPersist {
-		err := g.Store.PersistTxsMap(txsMap)
+	// every sender pays the same recipients, so the calldata is packed once
+	calldata := make([][]byte, len(g.Recipients))
+	for i, recipient := range g.Recipients {
+		calldata[i], err = PackCallData(erc20.MyTokenABI, "transfer", common.HexToAddress(recipient), amount)
 		if err != nil {
-			return txsMap, err
+			return nil, err
 		}
 	}
 
-	return txsMap, nil
+	return func(client *ethclient.Client, senderIndex int, sender *account.Account, index int, nonce uint64) (*types.Transaction, error) {
+		return plan.build(client, sender.Address, func(gasLimit uint64) (*types.Transaction, error) {
+			return GenerateCallDataTx(sender.PrivateKey, contractAddress, nonce, g.ChainID, g.Fees, gasLimit, calldata[index])
+		})
+	}, nil
 }
 
 func (g *Generator) prepareContractERC20() (common.Address, error) {
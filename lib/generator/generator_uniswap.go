@@ -4,92 +4,133 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"math/big"
 	"os"
-	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/0glabs/evmchainbench/lib/account"
 	"github.com/0glabs/evmchainbench/lib/contract_meta_data/erc20"
 	"github.com/0glabs/evmchainbench/lib/contract_meta_data/uniswap"
 )
 
+// Deadlines of the Uniswap workload. Persisted workloads may be loaded long
+// after they were generated, so their deadline lies far in the future.
+const (
+	defaultSwapDeadline   = 15 * time.Minute
+	persistedSwapDeadline = 10 * 365 * 24 * time.Hour
+)
+
 func (g *Generator) GenerateUniswap() (map[int]types.Transactions, error) {
-	txsMap := make(map[int]types.Transactions)
+	return g.Generate("uniswap")
+}
 
+// uniswapWorkload deploys two tokens and a Uniswap V2 pair with liquidity,
+// the txs of the senders then swap one token for the other.
+func (g *Generator) uniswapWorkload() (build txBuilder, err error) {
 	if g.ShouldPersist {
-		defer g.Store.PersistPrepareTxs()
+		defer g.persistPrepareTxs(&err)
 	}
 
+	// liquidity is added and swapped with the same deadline, the prepare txs
+	// are replayed by load as well
+	deadline := big.NewInt(time.Now().Add(g.SwapDeadline).Unix())
+	g.Deadline = deadline.Int64()
+
 	tokenA, err := g.deployContract(erc20ContractGasLimit, erc20.MyTokenBin, erc20.MyTokenABI, "Token A", "TOKENA")
 	if err != nil {
-		return txsMap, err
+		return nil, err
 	}
 
 	tokenB, err := g.deployContract(erc20ContractGasLimit, erc20.MyTokenBin, erc20.MyTokenABI, "Token B", "TOKENB")
 	if err != nil {
-		return txsMap, err
+		return nil, err
 	}
 
 	fmt.Println("Token A:", tokenA.Hex(), "Token B:", tokenB.Hex())
 
-	g.prepareSenders()
-	g.prepareERC20(tokenA.Hex())
-	g.prepareERC20(tokenB.Hex())
-
-	var data []interface{}
+	err = g.prepareSenders()
+	if err != nil {
+		return nil, err
+	}
+	err = g.prepareERC20(tokenA.Hex())
+	if err != nil {
+		return nil, err
+	}
+	err = g.prepareERC20(tokenB.Hex())
+	if err != nil {
+		return nil, err
+	}
 
-	factory, router := g.prepareContractUniswap()
+	factory, router, err := g.prepareContractUniswap()
+	if err != nil {
+		return nil, err
+	}
 	fmt.Println("Factory contract:", factory.Hex())
 	fmt.Println("Router contract:", router.Hex())
 
-	g.approveERC20(tokenA, router)
-	g.approveERC20(tokenB, router)
+	err = g.approveERC20(tokenA, router)
+	if err != nil {
+		return nil, err
+	}
+	err = g.approveERC20(tokenB, router)
+	if err != nil {
+		return nil, err
+	}
 
-	data = g.callContractView(tokenA, uniswap.UniswapV2ERC20ABI, "balanceOf", g.FaucetAccount.Address)
-	fmt.Println("Token A balance: ", data[0].(*big.Int).String())
-	data = g.callContractView(tokenA, uniswap.UniswapV2ERC20ABI, "allowance", g.FaucetAccount.Address, router)
-	fmt.Println("Token A allowance: ", data[0].(*big.Int).String())
-	data = g.callContractView(tokenB, uniswap.UniswapV2ERC20ABI, "balanceOf", g.FaucetAccount.Address)
-	fmt.Println("Token B balance: ", data[0].(*big.Int).String())
-	data = g.callContractView(tokenB, uniswap.UniswapV2ERC20ABI, "allowance", g.FaucetAccount.Address, router)
-	fmt.Println("Token B allowance: ", data[0].(*big.Int).String())
+	views := []struct {
+		label    string
+		contract common.Address
+		method   string
+		args     []interface{}
+	}{
+		{"Token A balance: ", tokenA, "balanceOf", []interface{}{g.FaucetAccount.Address}},
+		{"Token A allowance: ", tokenA, "allowance", []interface{}{g.FaucetAccount.Address, router}},
+		{"Token B balance: ", tokenB, "balanceOf", []interface{}{g.FaucetAccount.Address}},
+		{"Token B allowance: ", tokenB, "allowance", []interface{}{g.FaucetAccount.Address, router}},
+	}
+	for _, view := range views {
+		data, err := g.callContractView(view.contract, uniswap.UniswapV2ERC20ABI, view.method, view.args...)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println(view.label, data[0].(*big.Int).String())
+	}
 
-	g.executeContractFunction(uniswapCreatePairGasLimit, factory, uniswap.UniswapV2FactoryABI, "createPair", tokenA, tokenB)
-	data = g.callContractView(factory, uniswap.UniswapV2FactoryABI, "getPair", tokenA, tokenB)
+	err = g.executeContractFunction(uniswapCreatePairGasLimit, factory, uniswap.UniswapV2FactoryABI, "createPair", tokenA, tokenB)
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.callContractView(factory, uniswap.UniswapV2FactoryABI, "getPair", tokenA, tokenB)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Println("Pair address: ", data[0].(common.Address).Hex())
 
-	var tx *types.Transaction
-	var ethCallTx ethereum.CallMsg
-	var estimateGas uint64
-
 	fmt.Println("Add liquidity")
 
-	g.executeContractFunction(uniswapCreatePairGasLimit, router, uniswap.UniswapV2RouterABI, "addLiquidity",
+	err = g.executeContractFunction(uniswapCreatePairGasLimit, router, uniswap.UniswapV2RouterABI, "addLiquidity",
 		tokenA, tokenB, big.NewInt(1000000000), big.NewInt(1000000000), big.NewInt(0), big.NewInt(0), g.FaucetAccount.Address,
-		big.NewInt(time.Now().Unix()+15*60))
-
-	var mutex sync.Mutex
-	ch := make(chan error)
+		deadline)
+	if err != nil {
+		return nil, err
+	}
 
 	sender := g.Senders[0]
 	path := []common.Address{
 		common.HexToAddress(tokenA.Hex()),
 		common.HexToAddress(tokenB.Hex()),
 	}
-	deadline := big.NewInt(time.Now().Unix() + 15*60)
 
-	tx = GenerateContractCallingTx(
+	tx, err := GenerateContractCallingTx(
 		sender.PrivateKey,
 		router.Hex(),
 		0,
 		g.ChainID,
-		g.GasPrice,
+		g.Fees,
 		uniswapSwapGasLimit,
 		uniswap.UniswapV2RouterABI,
 		"swapExactTokensForTokens",
@@ -99,56 +140,30 @@ func (g *Generator) GenerateUniswap() (map[int]types.Transactions, error) {
 		sender.Address,
 		deadline,
 	)
-	ethCallTx = ConvertLegacyTxToCallMsg(tx, sender.Address)
-	estimateGas = g.estimateGas(ethCallTx)
-	estimateGas = (uint64)(1.2 * float64(estimateGas))
-
-	fmt.Println("Estimated gas:", estimateGas)
-
-	for index, sender := range g.Senders {
-		go func(index int, sender *account.Account) {
-			txs := types.Transactions{}
-			for range g.Recipients {
-				tx := GenerateContractCallingTx(
-					sender.PrivateKey,
-					router.Hex(),
-					sender.GetNonce(),
-					g.ChainID,
-					g.GasPrice,
-					estimateGas,
-					uniswap.UniswapV2RouterABI,
-					"swapExactTokensForTokens",
-					big.NewInt(1000),
-					big.NewInt(0),
-					path,
-					sender.Address,
-					deadline,
-				)
-				txs = append(txs, tx)
-			}
-
-			mutex.Lock()
-			txsMap[index] = txs
-			mutex.Unlock()
-			ch <- nil
-		}(index, sender)
-	}
-
-	for i := 0; i < len(g.Senders); i++ {
-		msg := <-ch
-		if msg != nil {
-			return txsMap, msg
-		}
+	if err != nil {
+		return nil, err
+	}
+	plan, err := g.planGas(uniswapSwapGasLimit, 1.2, tx)
+	if err != nil {
+		return nil, err
 	}
 
-	if g.ShouldPersist {
-		err := g.Store.PersistTxsMap(txsMap)
+	// the swaps of a sender only differ in their nonce, so the calldata is
+	// packed once per sender
+	calldata := make([][]byte, len(g.Senders))
+	for i, sender := range g.Senders {
+		calldata[i], err = PackCallData(uniswap.UniswapV2RouterABI, "swapExactTokensForTokens",
+			big.NewInt(1000), big.NewInt(0), path, sender.Address, deadline)
 		if err != nil {
-			return txsMap, err
+			return nil, err
 		}
 	}
 
-	return txsMap, nil
+	return func(client *ethclient.Client, senderIndex int, sender *account.Account, index int, nonce uint64) (*types.Transaction, error) {
+		return plan.build(client, sender.Address, func(gasLimit uint64) (*types.Transaction, error) {
+			return GenerateCallDataTx(sender.PrivateKey, router, nonce, g.ChainID, g.Fees, gasLimit, calldata[senderIndex])
+		})
+	}, nil
 }
 
 type Contract struct {
@@ -156,48 +171,54 @@ type Contract struct {
 	Bytecode string        `json:"bytecode"`
 }
 
-func ReadContract(filePath string) (string, string) {
+func ReadContract(filePath string) (string, string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatalf("Failed to open file: %v", err)
+		return "", "", fmt.Errorf("failed to open contract %s: %w", filePath, err)
 	}
 	defer file.Close()
 
 	fileData, err := io.ReadAll(file)
 	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
+		return "", "", fmt.Errorf("failed to read contract %s: %w", filePath, err)
 	}
 
 	var contract Contract
 	err = json.Unmarshal(fileData, &contract)
 	if err != nil {
-		log.Fatalf("Failed to unmarshal JSON: %v", err)
+		return "", "", fmt.Errorf("failed to unmarshal contract %s: %w", filePath, err)
 	}
 
 	abiJSON, err := json.Marshal(contract.Abi)
 	if err != nil {
-		log.Fatalf("Failed to marshal ABI: %v", err)
+		return "", "", fmt.Errorf("failed to marshal ABI of %s: %w", filePath, err)
 	}
 
-	return string(abiJSON), contract.Bytecode
+	return string(abiJSON), contract.Bytecode, nil
 }
 
-func (g *Generator) prepareContractUniswap() (common.Address, common.Address) {
-	factoryABI, factoryBin := ReadContract("contracts/UniswapV2Factory.json")
+func (g *Generator) prepareContractUniswap() (common.Address, common.Address, error) {
+	factoryABI, factoryBin, err := ReadContract("contracts/UniswapV2Factory.json")
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
 	factoryContract, err := g.deployContract(uniswapContractGasLimit, factoryBin, factoryABI, g.FaucetAccount.Address)
 	if err != nil {
-		panic(err)
+		return common.Address{}, common.Address{}, fmt.Errorf("failed to deploy Uniswap factory: %w", err)
 	}
 	fmt.Println("Uniswap Factory:", factoryContract.Hex())
 
-	routerABI, routerBin := ReadContract("contracts/UniswapV2Router02.json")
+	routerABI, routerBin, err := ReadContract("contracts/UniswapV2Router02.json")
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
 
 	uniswap.UniswapV2RouterABI = routerABI
 	routerContract, err := g.deployContract(uniswapContractGasLimit, routerBin, uniswap.UniswapV2RouterABI, factoryContract, factoryContract)
 	if err != nil {
-		panic(err)
+		return common.Address{}, common.Address{}, fmt.Errorf("failed to deploy Uniswap router: %w", err)
 	}
 	fmt.Println("Uniswap Router:", routerContract.Hex())
 
-	return factoryContract, routerContract
+	return factoryContract, routerContract, nil
 }
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// Manifest describes the txs of workload txType the generator is about to
+// generate. It must be taken before the workload is prepared, as it records
+// the nonces the accounts start at. The fees may still be bumped while
+// preparing, RecordFees updates them once the workload is generated.
+func (g *Generator) Manifest(txType string) *store.Manifest {
+	manifest := &store.Manifest{
+		ChainID: g.ChainID,
+		TxType:  txType,
+		Faucet: store.ManifestAccount{
+			Address: g.FaucetAccount.Address,
+			Nonce:   g.FaucetAccount.PeekNonce(),
+		},
+		TxCount:   len(g.Recipients),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	g.RecordFees(manifest)
+
+	for _, sender := range g.Senders {
+		manifest.Senders = append(manifest.Senders, store.ManifestAccount{
+			Address:    sender.Address,
+			Nonce:      sender.PeekNonce(),
+			PrivateKey: crypto.FromECDSA(sender.PrivateKey),
+		})
+	}
+
+	return manifest
+}
+
+// RecordFees sets the envelope and the fees of manifest to those the
+// generator signs with now.
+func (g *Generator) RecordFees(manifest *store.Manifest) {
+	manifest.TxEnvelope = g.Fees.TxType
+	manifest.GasTipCap, manifest.GasFeeCap, manifest.GasPrice = nil, nil, nil
+	if g.Fees.TxType == DynamicFeeTxType {
+		manifest.GasTipCap = g.Fees.GasTipCap
+		manifest.GasFeeCap = g.Fees.GasFeeCap
+	} else {
+		manifest.GasPrice = g.Fees.GasPrice
+	}
+}
@@ -0,0 +1,324 @@
+package generator
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/account"
+	"github.com/0glabs/evmchainbench/lib/store"
+)
+
+// txBuilder signs the index-th workload tx of the sender with the given index.
+// The client can be used to estimate gas, every worker has its own.
+type txBuilder func(client *ethclient.Client, senderIndex int, sender *account.Account, index int, nonce uint64) (*types.Transaction, error)
+
+// signChunk is the number of consecutive txs of a sender a worker signs in
+// one go.
+const signChunk = 64
+
+type signJob struct {
+	senderIndex int
+	index       int
+	nonce       uint64
+	count       int
+	deliver     func(txs types.Transactions, err error)
+}
+
+type signResult struct {
+	txs types.Transactions
+	err error
+}
+
+// Generate prepares the workload of txType and signs one tx per recipient for
+// every sender.
+func (g *Generator) Generate(txType string) (map[int]types.Transactions, error) {
+	build, err := g.workload(txType)
+	if err != nil {
+		return make(map[int]types.Transactions), err
+	}
+
+	return g.generateTxs(build)
+}
+
+// Stream prepares the workload of txType like Generate, but hands out the txs
+// while they are signed. At most buffer txs per sender wait for the consumer,
+// so sending can start right away and memory stays flat however many txs
+// there are.
+func (g *Generator) Stream(txType string, buffer int) (*store.TxStream, error) {
+	build, err := g.workload(txType)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.streamTxs(build, buffer)
+}
+
+func (g *Generator) workload(txType string) (txBuilder, error) {
+	switch txType {
+	case "simple":
+		return g.simpleWorkload()
+	case "erc20":
+		return g.erc20Workload()
+	case "uniswap":
+		return g.uniswapWorkload()
+	default:
+		return nil, fmt.Errorf("transaction type \"%v\" is not valid", txType)
+	}
+}
+
+func (g *Generator) workerCount() int {
+	if g.Workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return g.Workers
+}
+
+// startWorkers starts the pool of workers signing jobs until jobs is closed.
+func (g *Generator) startWorkers(build txBuilder, jobs <-chan signJob) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for w := 0; w < g.workerCount(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client, dialErr := ethclient.Dial(g.RpcUrl)
+			if dialErr == nil {
+				defer client.Close()
+			}
+
+			for job := range jobs {
+				if dialErr != nil {
+					job.deliver(nil, dialErr)
+					continue
+				}
+
+				txs := make(types.Transactions, 0, job.count)
+				for i := 0; i < job.count; i++ {
+					tx, err := build(client, job.senderIndex, g.Senders[job.senderIndex], job.index+i, job.nonce+uint64(i))
+					if err != nil {
+						job.deliver(nil, fmt.Errorf("failed to sign tx %d of sender %d: %w", job.index+i, job.senderIndex, err))
+						break
+					}
+					txs = append(txs, tx)
+				}
+				if len(txs) == job.count {
+					job.deliver(txs, nil)
+				}
+			}
+		}()
+	}
+	return &wg
+}
+
+// generateTxs signs one tx per recipient for every sender. Nonces are
+// reserved up front, so the txs are signed by a pool of workers in any order
+// while every sender still gets consecutive nonces.
+func (g *Generator) generateTxs(build txBuilder) (map[int]types.Transactions, error) {
+	txsMap := make(map[int]types.Transactions)
+	txCount := len(g.Recipients)
+	start := time.Now()
+
+	// the workers only fill in slots, the map itself must not grow concurrently
+	for index := range g.Senders {
+		txsMap[index] = make(types.Transactions, txCount)
+	}
+
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+
+	jobs := make(chan signJob, 1024)
+	wg := g.startWorkers(build, jobs)
+
+feed:
+	for index, sender := range g.Senders {
+		first := sender.ReserveNonces(uint64(txCount))
+		for i := 0; i < txCount; i += signChunk {
+			txs := txsMap[index][i:]
+			job := signJob{
+				senderIndex: index,
+				index:       i,
+				nonce:       first + uint64(i),
+				count:       min(signChunk, txCount-i),
+				deliver: func(signed types.Transactions, err error) {
+					if err != nil {
+						once.Do(func() {
+							firstErr = err
+							close(failed)
+						})
+						return
+					}
+					copy(txs, signed)
+				},
+			}
+
+			select {
+			case jobs <- job:
+			case <-failed:
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return txsMap, firstErr
+	}
+
+	g.printThroughput(txCount*len(g.Senders), start)
+
+	if g.ShouldPersist {
+		err := g.Store.PersistTxsMap(txsMap)
+		if err != nil {
+			return txsMap, err
+		}
+	}
+
+	return txsMap, nil
+}
+
+// streamTxs signs one tx per recipient for every sender like generateTxs.
+// Chunks of txs are signed by the worker pool in parallel, but handed out in
+// nonce order per sender. When persisting, every tx is appended to the tx file
+// of its sender before it is handed out.
+func (g *Generator) streamTxs(build txBuilder, buffer int) (*store.TxStream, error) {
+	txCount := len(g.Recipients)
+	start := time.Now()
+
+	writers := make([]*store.TxWriter, len(g.Senders))
+	if g.ShouldPersist {
+		for index := range g.Senders {
+			w, err := g.Store.CreateTxsWriter(index)
+			if err != nil {
+				for _, w := range writers[:index] {
+					w.Close()
+				}
+				return nil, fmt.Errorf("failed to create tx file of sender %d: %w", index, err)
+			}
+			writers[index] = w
+		}
+	}
+
+	errCh := make(chan error, 1)
+	failed := make(chan struct{})
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			errCh <- err
+			close(failed)
+		})
+	}
+
+	jobs := make(chan signJob, g.workerCount())
+	wg := g.startWorkers(build, jobs)
+
+	streams := make([]<-chan *types.Transaction, len(g.Senders))
+	var feeders, emitters sync.WaitGroup
+	for index, sender := range g.Senders {
+		out := make(chan *types.Transaction, buffer)
+		streams[index] = out
+		// results of the chunks in flight, in nonce order
+		pending := make(chan chan signResult, max(1, buffer/signChunk))
+
+		feeders.Add(1)
+		go func(index int, sender *account.Account) {
+			defer feeders.Done()
+			defer close(pending)
+
+			first := sender.ReserveNonces(uint64(txCount))
+			for i := 0; i < txCount; i += signChunk {
+				result := make(chan signResult, 1)
+				job := signJob{
+					senderIndex: index,
+					index:       i,
+					nonce:       first + uint64(i),
+					count:       min(signChunk, txCount-i),
+					deliver: func(txs types.Transactions, err error) {
+						result <- signResult{txs: txs, err: err}
+					},
+				}
+
+				select {
+				case pending <- result:
+				case <-failed:
+					return
+				}
+				select {
+				case jobs <- job:
+				case <-failed:
+					return
+				}
+			}
+		}(index, sender)
+
+		emitters.Add(1)
+		go func(w *store.TxWriter) {
+			defer emitters.Done()
+			defer close(out)
+			if w != nil {
+				defer func() {
+					err := w.Close()
+					if err != nil {
+						fail(err)
+					}
+				}()
+			}
+
+			for result := range pending {
+				var r signResult
+				select {
+				case r = <-result:
+				case <-failed:
+					return
+				}
+				if r.err != nil {
+					fail(r.err)
+					return
+				}
+				for _, tx := range r.txs {
+					if w != nil {
+						err := w.Write(tx)
+						if err != nil {
+							fail(err)
+							return
+						}
+					}
+					select {
+					case out <- tx:
+					case <-failed:
+						return
+					}
+				}
+			}
+		}(writers[index])
+	}
+
+	go func() {
+		feeders.Wait()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	go func() {
+		emitters.Wait()
+		select {
+		case <-failed:
+		default:
+			g.printThroughput(txCount*len(g.Senders), start)
+		}
+		close(errCh)
+	}()
+
+	return &store.TxStream{Txs: streams, Err: errCh}, nil
+}
+
+func (g *Generator) printThroughput(total int, start time.Time) {
+	elapsed := time.Since(start)
+	fmt.Printf("Generated %d txs in %v with %d workers (%.0f tx/s)\n", total, elapsed.Round(time.Millisecond), g.workerCount(), float64(total)/elapsed.Seconds())
+}
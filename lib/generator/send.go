@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/0glabs/evmchainbench/lib/account"
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+const receiptTimeout = 20 * time.Second
+
+// replacementFeeBump is the lowest percentage by which a replacement tx must
+// outbid the tx it replaces. Geth rejects replacements below 10%.
+const replacementFeeBump = 10
+
+// sendTx builds a tx with the next nonce of sender and sends it. If the node
+// rejects the nonce, the nonce is resynced from the chain and the tx is built
+// and sent once more. If the node rejects the tx as underpriced, the fees are
+// bumped and the tx is built again with the same nonce.
+func (g *Generator) sendTx(client *ethclient.Client, sender *account.Account, build func(nonce uint64) (*types.Transaction, error)) (*types.Transaction, error) {
+	nonce := sender.GetNonce()
+	tx, err := build(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx of %s with nonce %d: %w", sender.Address.Hex(), nonce, err)
+	}
+
+	err = client.SendTransaction(context.Background(), tx)
+	if util.IsNonceError(err) {
+		resynced, resyncErr := sender.ResyncNonce(client)
+		if resyncErr != nil {
+			return nil, resyncErr
+		}
+		fmt.Printf("Nonce of %s resynced to %d: %v\n", sender.Address.Hex(), resynced, err)
+
+		nonce = sender.GetNonce()
+		tx, err = build(nonce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign tx of %s with nonce %d: %w", sender.Address.Hex(), nonce, err)
+		}
+		err = client.SendTransaction(context.Background(), tx)
+	}
+	for bumps := 0; util.IsUnderpricedError(err) && bumps < g.FeeStrategy.MaxBumps; bumps++ {
+		g.Fees = g.FeeStrategy.Bump(g.Fees)
+		fmt.Printf("Fees bumped to %v: %v\n", g.Fees, err)
+
+		tx, err = build(nonce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign tx of %s with nonce %d: %w", sender.Address.Hex(), nonce, err)
+		}
+		err = client.SendTransaction(context.Background(), tx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send tx %s of %s: %w", tx.Hash().Hex(), sender.Address.Hex(), err)
+	}
+
+	if g.ShouldPersist {
+		g.Store.AddPrepareTx(tx)
+	}
+
+	return tx, nil
+}
+
+// waitForReceipts waits for the receipts of txs. When they do not show up in
+// time and FillNonceGaps is set, nonce gaps of the prepare accounts are
+// filled and the receipts are waited for once more.
+func (g *Generator) waitForReceipts(client *ethclient.Client, txs types.Transactions) error {
+	err := util.WaitForReceiptsOfTxs(client, txs, receiptTimeout)
+	if err == nil || !g.FillNonceGaps {
+		return err
+	}
+
+	filled, fillErr := g.fillNonceGaps(client, txs)
+	if fillErr != nil {
+		return fillErr
+	}
+	if !filled {
+		return err
+	}
+
+	return util.WaitForReceiptsOfTxs(client, txs, receiptTimeout)
+}
+
+type accountNonce struct {
+	address common.Address
+	nonce   uint64
+}
+
+// fillNonceGaps closes the nonce gaps of the faucet and the senders. A missing
+// nonce that belongs to one of txs is closed by sending that tx again, any
+// other missing nonce is closed with a replacement self transfer.
+func (g *Generator) fillNonceGaps(client *ethclient.Client, txs types.Transactions) (bool, error) {
+	signer := types.LatestSignerForChainID(g.ChainID)
+	known := make(map[accountNonce]*types.Transaction)
+	for _, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return false, err
+		}
+		known[accountNonce{from, tx.Nonce()}] = tx
+	}
+
+	filled := false
+	for _, acc := range append([]*account.Account{g.FaucetAccount}, g.Senders...) {
+		from, to, err := acc.NonceGap(client)
+		if err != nil {
+			return filled, err
+		}
+		if from == to {
+			continue
+		}
+
+		fmt.Printf("Filling nonce gap [%d, %d) of %s\n", from, to, acc.Address.Hex())
+		for nonce := from; nonce < to; nonce++ {
+			if tx, ok := known[accountNonce{acc.Address, nonce}]; ok {
+				err = client.SendTransaction(context.Background(), tx)
+				if err != nil && !util.IsAlreadyKnownError(err) {
+					return filled, err
+				}
+				continue
+			}
+
+			fillers, err := acc.FillNonceGap(client, nonce, nonce+1, func(nonce uint64) (*types.Transaction, error) {
+				return g.fillerTx(acc, nonce)
+			})
+			if err != nil {
+				return filled, err
+			}
+			if g.ShouldPersist {
+				for _, tx := range fillers {
+					err = g.Store.InsertPrepareTx(tx)
+					if err != nil {
+						return filled, err
+					}
+				}
+			}
+		}
+		filled = true
+	}
+
+	return filled, nil
+}
+
+// fillerTx signs a zero value self transfer of acc with the given nonce. Its
+// fees are bumped so that it also replaces a tx stuck in the mempool.
+func (g *Generator) fillerTx(acc *account.Account, nonce uint64) (*types.Transaction, error) {
+	bump := FeeStrategy{BumpPercent: max(g.FeeStrategy.BumpPercent, replacementFeeBump)}
+	return signTx(acc.PrivateKey, g.ChainID, bump.Bump(g.Fees), nonce, &acc.Address, big.NewInt(0), simpleTransferGasLimit, nil)
+}
+
+// FillerTx signs a filler tx for the given nonce of sender, closing a nonce
+// gap left by a dropped tx of the workload.
+func (g *Generator) FillerTx(sender common.Address, nonce uint64) (*types.Transaction, error) {
+	for _, acc := range append([]*account.Account{g.FaucetAccount}, g.Senders...) {
+		if acc.Address == sender {
+			return g.fillerTx(acc, nonce)
+		}
+	}
+
+	return nil, fmt.Errorf("no private key of sender %s", sender.Hex())
+}
+
+// persistPrepareTxs stores the prepare txs sent so far. It is deferred by the
+// workloads, so a failure is reported through err unless the workload already
+// returns an error.
+func (g *Generator) persistPrepareTxs(err *error) {
+	persistErr := g.Store.PersistPrepareTxs()
+	if persistErr != nil && *err == nil {
+		*err = fmt.Errorf("failed to persist prepare txs: %w", persistErr)
+	}
+}
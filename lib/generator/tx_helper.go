@@ -3,8 +3,10 @@ package generator
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	abipkg "github.com/ethereum/go-ethereum/accounts/abi"
@@ -12,108 +14,172 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
-func GenerateSimpleTransferTx(privateKey *ecdsa.PrivateKey, recipient string, nonce uint64, chainID, gasPrice, value *big.Int, eip1559 bool) (*types.Transaction, error) {
-	toAddress := common.HexToAddress(recipient)
+// Envelopes of generated txs
+const (
+	LegacyTxType     = "legacy"
+	AccessListTxType = "access-list"
+	DynamicFeeTxType = "dynamic-fee"
+)
 
-	var signedTx *types.Transaction
-	var err error
-	if eip1559 {
-		tx := types.NewTx(&types.DynamicFeeTx{
-			ChainID:   chainID,
-			Nonce:     nonce,
-			To:        &toAddress,
-			Value:     value,
-			GasFeeCap: gasPrice,
-			GasTipCap: gasPrice,
-			Gas:       simpleTransferGasLimit,
-			Data:      nil,
-		})
-		signedTx, err = types.SignTx(tx, types.NewLondonSigner(chainID), privateKey)
-	} else {
-		tx := types.NewTransaction(nonce, toAddress, value, simpleTransferGasLimit, gasPrice, nil)
-		signedTx, err = types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+// TxFees selects the envelope of a tx and the fees it pays. GasPrice is used
+// by legacy and access-list txs, GasTipCap and GasFeeCap by dynamic-fee txs.
+type TxFees struct {
+	TxType    string
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// PriceCap returns the highest price per gas a tx with these fees may pay.
+func (f TxFees) PriceCap() *big.Int {
+	if f.TxType == DynamicFeeTxType {
+		return f.GasFeeCap
 	}
+	return f.GasPrice
+}
 
-	if err != nil {
-		return &types.Transaction{}, err
+func (f TxFees) String() string {
+	if f.TxType == DynamicFeeTxType {
+		return fmt.Sprintf("%s tip=%v feeCap=%v", f.TxType, f.GasTipCap, f.GasFeeCap)
 	}
+	return fmt.Sprintf("%s gasPrice=%v", f.TxType, f.GasPrice)
+}
 
-	return signedTx, nil
+func GenerateSimpleTransferTx(privateKey *ecdsa.PrivateKey, recipient string, nonce uint64, chainID *big.Int, fees TxFees, value *big.Int) (*types.Transaction, error) {
+	toAddress := common.HexToAddress(recipient)
+
+	return signTx(privateKey, chainID, fees, nonce, &toAddress, value, simpleTransferGasLimit, nil)
 }
 
-func GenerateContractCreationTx(privateKey *ecdsa.PrivateKey, nonce uint64, chainID, gasPrice *big.Int, gasLimit uint64, contractBin, contractABI string, args ...interface{}) (*types.Transaction, error) {
+func GenerateContractCreationTx(privateKey *ecdsa.PrivateKey, nonce uint64, chainID *big.Int, fees TxFees, gasLimit uint64, contractBin, contractABI string, args ...interface{}) (*types.Transaction, error) {
 	bytecode, err := hex.DecodeString(contractBin)
 	if err != nil {
 		return &types.Transaction{}, err
 	}
 
 	if len(args) > 0 {
-		abi, err := abipkg.JSON(strings.NewReader(contractABI))
+		abi, err := parseABI(contractABI)
 		if err != nil {
 			return &types.Transaction{}, err
 		}
 
 		inputData, err := abi.Pack("", args...)
 		if err != nil {
-			panic(err)
+			return &types.Transaction{}, fmt.Errorf("failed to pack constructor arguments: %w", err)
 		}
 
 		bytecode = append(bytecode, inputData...)
 
 	}
 
-	tx := types.NewContractCreation(
-		nonce,
-		big.NewInt(0),
-		gasLimit,
-		gasPrice,
-		bytecode,
-	)
+	return signTx(privateKey, chainID, fees, nonce, nil, big.NewInt(0), gasLimit, bytecode)
+}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+func ConvertTxToCallMsg(tx *types.Transaction, from common.Address) ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasFeeCap = tx.GasFeeCap()
+		msg.GasTipCap = tx.GasTipCap()
+	} else {
+		msg.GasPrice = tx.GasPrice()
+	}
+
+	return msg
+}
+
+func GenerateContractCallingTx(privateKey *ecdsa.PrivateKey, contractAddress string, nonce uint64, chainID *big.Int, fees TxFees, gasLimit uint64, contractABI, method string, args ...interface{}) (*types.Transaction, error) {
+	data, err := PackCallData(contractABI, method, args...)
 	if err != nil {
-		return &types.Transaction{}, err
+		return &types.Transaction{}, fmt.Errorf("failed to pack call of %s: %w", method, err)
 	}
 
-	return signedTx, nil
+	return GenerateCallDataTx(privateKey, common.HexToAddress(contractAddress), nonce, chainID, fees, gasLimit, data)
 }
 
-func ConvertLegacyTxToCallMsg(tx *types.Transaction, from common.Address) ethereum.CallMsg {
-	return ethereum.CallMsg{
-		From:     from,
-		To:       tx.To(),
-		Gas:      tx.Gas(),
-		GasPrice: tx.GasPrice(),
-		Value:    tx.Value(),
-		Data:     tx.Data(),
+// GenerateCallDataTx signs a contract call with calldata packed beforehand,
+// so that a template packed once can be reused for many txs.
+func GenerateCallDataTx(privateKey *ecdsa.PrivateKey, contractAddress common.Address, nonce uint64, chainID *big.Int, fees TxFees, gasLimit uint64, data []byte) (*types.Transaction, error) {
+	return signTx(privateKey, chainID, fees, nonce, &contractAddress, big.NewInt(0), gasLimit, data)
+}
+
+// PackCallData encodes the call of method with args.
+func PackCallData(contractABI, method string, args ...interface{}) ([]byte, error) {
+	abi, err := parseABI(contractABI)
+	if err != nil {
+		return nil, err
 	}
+
+	return abi.Pack(method, args...)
 }
 
-func GenerateContractCallingTx(privateKey *ecdsa.PrivateKey, contractAddress string, nonce uint64, chainID, gasPrice *big.Int, gasLimit uint64, contractABI, method string, args ...interface{}) *types.Transaction {
+// parsedABIs caches parsed ABIs by their JSON, parsing is far more expensive
+// than packing calldata.
+var parsedABIs sync.Map
+
+func parseABI(contractABI string) (*abipkg.ABI, error) {
+	if cached, ok := parsedABIs.Load(contractABI); ok {
+		return cached.(*abipkg.ABI), nil
+	}
+
 	abi, err := abipkg.JSON(strings.NewReader(contractABI))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	data, err := abi.Pack(method, args...)
-	if err != nil {
-		panic(err)
+	parsedABIs.Store(contractABI, &abi)
+	return &abi, nil
+}
+
+// signTx builds a tx in the envelope selected by fees and signs it. A nil to
+// creates a contract.
+func signTx(privateKey *ecdsa.PrivateKey, chainID *big.Int, fees TxFees, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, data []byte) (*types.Transaction, error) {
+	var txData types.TxData
+	switch fees.TxType {
+	case LegacyTxType:
+		txData = &types.LegacyTx{
+			Nonce:    nonce,
+			To:       to,
+			Value:    value,
+			Gas:      gasLimit,
+			GasPrice: fees.GasPrice,
+			Data:     data,
+		}
+	case AccessListTxType:
+		txData = &types.AccessListTx{
+			ChainID:  chainID,
+			Nonce:    nonce,
+			To:       to,
+			Value:    value,
+			Gas:      gasLimit,
+			GasPrice: fees.GasPrice,
+			Data:     data,
+		}
+	case DynamicFeeTxType:
+		txData = &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			To:        to,
+			Value:     value,
+			Gas:       gasLimit,
+			GasTipCap: fees.GasTipCap,
+			GasFeeCap: fees.GasFeeCap,
+			Data:      data,
+		}
+	default:
+		return &types.Transaction{}, fmt.Errorf("tx envelope \"%v\" is not valid", fees.TxType)
 	}
 
-	toAddress := common.HexToAddress(contractAddress)
-	tx := types.NewTransaction(
-		nonce,
-		toAddress,
-		big.NewInt(0),
-		gasLimit,
-		gasPrice,
-		data,
-	)
-
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(chainID), txData)
 	if err != nil {
-		panic(err)
+		return &types.Transaction{}, err
 	}
 
-	return signedTx
+	return signedTx, nil
 }
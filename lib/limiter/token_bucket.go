@@ -0,0 +1,80 @@
+package run
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket hands out tokens at a rate that may change over time. While
+// nobody takes them, at most burst tokens pile up, so a taker that fell behind
+// catches up with a short burst instead of flooding.
+type TokenBucket struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	burst    int
+	// next is the time the next token becomes available at
+	next    time.Time
+	stopped bool
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	tb := &TokenBucket{next: time.Now()}
+	tb.SetRate(rate, burst)
+	return tb
+}
+
+// SetRate changes the rate tokens are handed out at. A rate of 0 pauses the
+// bucket.
+func (tb *TokenBucket) SetRate(rate float64, burst int) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tb.interval = 0
+	if rate > 0 {
+		tb.interval = time.Duration(float64(time.Second) / rate)
+	}
+	tb.burst = max(1, burst)
+
+	// a token scheduled at a lower rate must not hold back the new one
+	if soonest := time.Now().Add(tb.interval); tb.next.After(soonest) {
+		tb.next = soonest
+	}
+}
+
+// Stop makes every pending and later Take return false.
+func (tb *TokenBucket) Stop() {
+	tb.mutex.Lock()
+	tb.stopped = true
+	tb.mutex.Unlock()
+}
+
+// Take blocks until a token is available and takes it. It returns false once
+// the bucket is stopped.
+func (tb *TokenBucket) Take() bool {
+	for {
+		tb.mutex.Lock()
+		if tb.stopped {
+			tb.mutex.Unlock()
+			return false
+		}
+		if tb.interval == 0 {
+			tb.mutex.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		now := time.Now()
+		earliest := now.Add(-time.Duration(tb.burst-1) * tb.interval)
+		if tb.next.Before(earliest) {
+			tb.next = earliest
+		}
+		at := tb.next
+		tb.next = tb.next.Add(tb.interval)
+		tb.mutex.Unlock()
+
+		if wait := at.Sub(now); wait > 0 {
+			time.Sleep(wait)
+		}
+		return true
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const checkpointFileName = "checkpoint.json"
+
+// Checkpoint records how far the workload txs of every sender were sent.
+type Checkpoint struct {
+	Senders   []SenderProgress `json:"senders"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// SenderProgress is the index in the tx file and the nonce of the last tx of
+// a sender that was sent. Sent is false while none was. Offset is where the
+// tx file continues after that tx, 0 if unknown, e.g. for legacy tx files.
+type SenderProgress struct {
+	Sent   bool   `json:"sent"`
+	Index  int    `json:"index"`
+	Nonce  uint64 `json:"nonce"`
+	Offset int64  `json:"offset,omitempty"`
+}
+
+// WriteCheckpoint replaces the checkpoint of the store. The file is written
+// aside and renamed, so an interruption never leaves a torn checkpoint.
+func (s *Store) WriteCheckpoint(checkpoint *Checkpoint) error {
+	err := os.MkdirAll(s.TxStoreDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.TxStoreDir, checkpointFileName)
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// ReadCheckpoint reads the checkpoint of the store, os.ErrNotExist is
+// returned if nothing was sent yet.
+func (s *Store) ReadCheckpoint() (*Checkpoint, error) {
+	path := filepath.Join(s.TxStoreDir, checkpointFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &checkpoint, nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of tx files. Readers detect the compression of a file by its
+// first bytes, so they need not know how a store was written.
+const (
+	NoCompression   = "none"
+	GzipCompression = "gzip"
+	ZstdCompression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ValidateCompression returns an error if compression is not one of the
+// compressions above. Empty means no compression.
+func ValidateCompression(compression string) error {
+	switch compression {
+	case "", NoCompression, GzipCompression, ZstdCompression:
+		return nil
+	default:
+		return fmt.Errorf("compression \"%v\" is not valid", compression)
+	}
+}
+
+// newCompressor wraps w in a compressor, it returns nil without compression.
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "", NoCompression:
+		return nil, nil
+	case GzipCompression:
+		return gzip.NewWriter(w), nil
+	case ZstdCompression:
+		return zstd.NewWriter(w)
+	default:
+		return nil, ValidateCompression(compression)
+	}
+}
+
+// newDecompressor detects the compression of r, it returns nil if r is not
+// compressed.
+func newDecompressor(r *bufio.Reader) (io.ReadCloser, error) {
+	head, err := r.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return gzip.NewReader(r)
+	case bytes.HasPrefix(head, zstdMagic):
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tx files are append-only sequences of frames behind a short magic header,
+// optionally compressed as a whole.
+// Every frame holds one tx in its binary encoding, prefixed by its length as
+// a 4-byte big-endian integer. Frames are buffered and written in chunks, so a
+// file can be written while txs are generated and read while txs are sent,
+// and reading can resume at the offset of any frame.
+
+var frameMagic = []byte("ECBTXS\x00\x01")
+
+const (
+	frameLengthSize = 4
+	// maxFrameSize guards against allocating garbage lengths of corrupt files.
+	maxFrameSize = 1 << 24
+	// chunkSize is the number of bytes buffered before frames hit the disk.
+	chunkSize = 1 << 20
+)
+
+// ErrNotFramed is returned when a file does not start with the frame magic.
+var ErrNotFramed = errors.New("not a framed tx file")
+
+// TxWriter appends txs to a tx file.
+type TxWriter struct {
+	file       *os.File
+	compressor io.WriteCloser
+	buf        *bufio.Writer
+	offset     int64
+	count      int
+	// onClose receives the stats of the file once it is closed.
+	onClose func(stats IOStats)
+}
+
+// CreateTxWriter creates the tx file at path, truncating an existing one.
+// Frames are compressed with compression unless it is empty or none.
+func CreateTxWriter(path, compression string) (*TxWriter, error) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := newCompressor(file, compression)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	var out io.Writer = file
+	if compressor != nil {
+		out = compressor
+	}
+
+	w := &TxWriter{
+		file:       file,
+		compressor: compressor,
+		buf:        bufio.NewWriterSize(out, chunkSize),
+	}
+	n, err := w.buf.Write(frameMagic)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.offset = int64(n)
+
+	return w, nil
+}
+
+// Write appends tx as one frame.
+func (w *TxWriter) Write(tx *types.Transaction) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode tx %s: %w", tx.Hash().Hex(), err)
+	}
+
+	var length [frameLengthSize]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	if _, err := w.buf.Write(length[:]); err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(data); err != nil {
+		return err
+	}
+
+	w.offset += int64(frameLengthSize + len(data))
+	w.count++
+	return nil
+}
+
+// Offset returns the offset the next frame will be written at. Offsets count
+// bytes before compression.
+func (w *TxWriter) Offset() int64 {
+	return w.offset
+}
+
+// FrameSize returns the number of bytes tx takes in a tx file, so the offset
+// of the frame after a tx is known without reading the file.
+func FrameSize(tx *types.Transaction) int64 {
+	return int64(frameLengthSize) + int64(tx.Size())
+}
+
+// Count returns the number of txs written so far.
+func (w *TxWriter) Count() int {
+	return w.count
+}
+
+// Close writes the buffered frames and closes the file.
+func (w *TxWriter) Close() error {
+	err := w.buf.Flush()
+	if err == nil && w.compressor != nil {
+		err = w.compressor.Close()
+	}
+	info, statErr := w.file.Stat()
+	closeErr := w.file.Close()
+	if err != nil {
+		return err
+	}
+	if statErr != nil {
+		return statErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if w.onClose != nil {
+		w.onClose(IOStats{Txs: w.count, RawBytes: w.offset, DiskBytes: info.Size()})
+	}
+	return nil
+}
+
+// TxReader reads the txs of a tx file one frame at a time.
+type TxReader struct {
+	file         *os.File
+	decompressor io.ReadCloser
+	buf          *bufio.Reader
+	path         string
+	offset       int64
+	count        int
+	size         int64
+}
+
+// OpenTxReader opens the tx file at path, which may be compressed. Reading
+// starts at offset, which must be the offset of a frame as returned by Offset
+// of a reader or writer of the same file. An offset of 0 starts at the first
+// frame.
+func OpenTxReader(path string, offset int64) (*TxReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	r := &TxReader{
+		file: file,
+		buf:  bufio.NewReaderSize(file, chunkSize),
+		path: path,
+		size: info.Size(),
+	}
+	r.decompressor, err = newDecompressor(r.buf)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.decompressor != nil {
+		r.buf = bufio.NewReaderSize(r.decompressor, chunkSize)
+	}
+
+	magic := make([]byte, len(frameMagic))
+	_, err = io.ReadFull(r.buf, magic)
+	if err != nil || !bytes.Equal(magic, frameMagic) {
+		r.Close()
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFramed)
+	}
+	r.offset = int64(len(frameMagic))
+
+	if offset > r.offset {
+		// compressed files cannot seek, their frames up to offset are skipped
+		if r.decompressor == nil {
+			_, err = file.Seek(offset, io.SeekStart)
+			r.buf.Reset(file)
+		} else {
+			_, err = io.CopyN(io.Discard, r.buf, offset-r.offset)
+		}
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%s: failed to skip to offset %d: %w", path, offset, err)
+		}
+		r.offset = offset
+	}
+
+	return r, nil
+}
+
+// Next returns the next tx, or io.EOF after the last one.
+func (r *TxReader) Next() (*types.Transaction, error) {
+	var length [frameLengthSize]byte
+	_, err := io.ReadFull(r.buf, length[:])
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: truncated frame at offset %d: %w", r.path, r.offset, err)
+	}
+
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("%s: frame of %d bytes at offset %d is too large", r.path, size, r.offset)
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r.buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: truncated frame at offset %d: %w", r.path, r.offset, err)
+	}
+
+	tx := new(types.Transaction)
+	err = tx.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decode frame at offset %d: %w", r.path, r.offset, err)
+	}
+
+	r.offset += int64(frameLengthSize) + int64(size)
+	r.count++
+	return tx, nil
+}
+
+// Offset returns the offset of the frame the next call of Next reads. Offsets
+// count bytes before compression.
+func (r *TxReader) Offset() int64 {
+	return r.offset
+}
+
+// Stats returns the txs read so far and the size of the file.
+func (r *TxReader) Stats() IOStats {
+	return IOStats{Txs: r.count, RawBytes: r.offset, DiskBytes: r.size}
+}
+
+func (r *TxReader) Close() error {
+	if r.decompressor != nil {
+		r.decompressor.Close()
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func signedTxs(t *testing.T, count int) types.Transactions {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1337))
+	to := common.HexToAddress("0x000000000000000000000000000000000000dead")
+
+	txs := make(types.Transactions, count)
+	for i := range txs {
+		txs[i], err = types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1337),
+			Nonce:     uint64(i),
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(2),
+			Gas:       21000,
+			To:        &to,
+			Value:     big.NewInt(int64(i)),
+			Data:      make([]byte, i%7),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return txs
+}
+
+func TestTxFileRoundTrip(t *testing.T) {
+	txs := signedTxs(t, 100)
+
+	for _, compression := range []string{NoCompression, GzipCompression, ZstdCompression} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transactions-0.txs")
+
+			w, err := CreateTxWriter(path, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const resumeAt = 37
+			var offset int64
+			for i, tx := range txs {
+				if i == resumeAt {
+					offset = w.Offset()
+				}
+				before := w.Offset()
+				err = w.Write(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if w.Offset()-before != FrameSize(tx) {
+					t.Fatalf("tx %d took %d bytes, FrameSize says %d", i, w.Offset()-before, FrameSize(tx))
+				}
+			}
+			if w.Count() != len(txs) {
+				t.Fatalf("wrote %d txs, want %d", w.Count(), len(txs))
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, start := range []struct {
+				offset int64
+				index  int
+			}{{0, 0}, {offset, resumeAt}} {
+				r, err := OpenTxReader(path, start.offset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := start.index; i < len(txs); i++ {
+					tx, err := r.Next()
+					if err != nil {
+						t.Fatalf("tx %d from offset %d: %v", i, start.offset, err)
+					}
+					if tx.Hash() != txs[i].Hash() {
+						t.Fatalf("tx %d from offset %d is %s, want %s", i, start.offset, tx.Hash().Hex(), txs[i].Hash().Hex())
+					}
+				}
+				_, err = r.Next()
+				if err != io.EOF {
+					t.Fatalf("read past the last tx from offset %d: %v", start.offset, err)
+				}
+				r.Close()
+			}
+		})
+	}
+}
+
+func TestTxFileCorruption(t *testing.T) {
+	txs := signedTxs(t, 2)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "transactions-0.txs")
+	w, err := CreateTxWriter(path, NoCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range txs {
+		if err := w.Write(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oversized := append([]byte(nil), frameMagic...)
+	oversized = binary.BigEndian.AppendUint32(oversized, maxFrameSize+1)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated frame", data[:len(data)-3]},
+		{"truncated length", append(append([]byte(nil), data...), 0, 0)},
+		{"oversized frame", oversized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, "corrupt.txs")
+			err := os.WriteFile(path, test.data, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := OpenTxReader(path, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for {
+				_, err = r.Next()
+				if err != nil {
+					break
+				}
+			}
+			if err == io.EOF {
+				t.Fatal("corrupt file read to the end without an error")
+			}
+		})
+	}
+
+	t.Run("not framed", func(t *testing.T) {
+		path := filepath.Join(dir, "legacy.rlp")
+		err := os.WriteFile(path, []byte("not a tx file"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = OpenTxReader(path, 0)
+		if !errors.Is(err, ErrNotFramed) {
+			t.Fatalf("got %v, want ErrNotFramed", err)
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// maxProblemsPerFile caps the problems Verify reports for one file, a broken
+// file tends to break every tx after the first problem.
+const maxProblemsPerFile = 10
+
+// FileSummary describes the txs of one tx file.
+type FileSummary struct {
+	Path    string
+	Count   int
+	Senders []common.Address
+	// FirstNonce and LastNonce are the nonces of the first and last tx.
+	FirstNonce uint64
+	LastNonce  uint64
+	// Types counts the txs of every envelope.
+	Types map[string]int
+	// Gas is the sum of the gas limits.
+	Gas uint64
+}
+
+// Inspection summarizes the contents of a store.
+type Inspection struct {
+	// Manifest is nil for stores written by older versions.
+	Manifest *Manifest
+	Prepare  *FileSummary
+	Files    []*FileSummary
+}
+
+// Inspect reads all tx files of the store and summarizes them.
+func (s *Store) Inspect() (*Inspection, error) {
+	inspection := &Inspection{}
+
+	manifest, err := s.ReadManifest()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	inspection.Manifest = manifest
+
+	if path, ok := s.existingPrepareFile(); ok {
+		inspection.Prepare, err = summarize(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths, err := s.TxFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		summary, err := summarize(path)
+		if err != nil {
+			return nil, err
+		}
+		inspection.Files = append(inspection.Files, summary)
+	}
+
+	return inspection, nil
+}
+
+func (i *Inspection) String() string {
+	var b strings.Builder
+	if i.Manifest != nil {
+		fmt.Fprintf(&b, "Manifest: %s txs for chain ID %v generated at %v\n", i.Manifest.TxType, i.Manifest.ChainID, i.Manifest.CreatedAt)
+		fmt.Fprintf(&b, "  Envelope: %s, price cap: %v per gas\n", i.Manifest.TxEnvelope, i.Manifest.PriceCap())
+		if i.Manifest.Deadline != 0 {
+			fmt.Fprintf(&b, "  Expires at %v\n", time.Unix(i.Manifest.Deadline, 0).UTC())
+		}
+	} else {
+		fmt.Fprintln(&b, "Manifest: none")
+	}
+	if i.Prepare != nil {
+		fmt.Fprintln(&b, "Prepare txs:")
+		fmt.Fprintln(&b, i.Prepare.String())
+	}
+
+	var count int
+	var gas uint64
+	fmt.Fprintln(&b, "Workload txs:")
+	for _, file := range i.Files {
+		fmt.Fprintln(&b, file.String())
+		count += file.Count
+		gas += file.Gas
+	}
+	fmt.Fprintf(&b, "Total: %d txs in %d files, %d gas", count, len(i.Files), gas)
+
+	return b.String()
+}
+
+func (f *FileSummary) String() string {
+	senders := make([]string, len(f.Senders))
+	for i, sender := range f.Senders {
+		senders[i] = sender.Hex()
+	}
+
+	types := make([]string, 0, len(f.Types))
+	for name, count := range f.Types {
+		types = append(types, fmt.Sprintf("%s=%d", name, count))
+	}
+	sort.Strings(types)
+
+	return fmt.Sprintf("  %s: %d txs, nonces %d-%d, gas %d, types %s, senders %s",
+		filepath.Base(f.Path), f.Count, f.FirstNonce, f.LastNonce, f.Gas, strings.Join(types, " "), strings.Join(senders, " "))
+}
+
+func summarize(path string) (*FileSummary, error) {
+	summary := &FileSummary{Path: path, Types: make(map[string]int)}
+	seen := make(map[common.Address]bool)
+
+	err := scanTxs(path, func(tx *types.Transaction) error {
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			return fmt.Errorf("%s: tx %s: %w", path, tx.Hash().Hex(), err)
+		}
+		if !seen[sender] {
+			seen[sender] = true
+			summary.Senders = append(summary.Senders, sender)
+		}
+
+		if summary.Count == 0 {
+			summary.FirstNonce = tx.Nonce()
+		}
+		summary.LastNonce = tx.Nonce()
+		summary.Types[txTypeName(tx.Type())]++
+		summary.Gas += tx.Gas()
+		summary.Count++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// Verify checks that every tx of the store, the prepare txs included, is
+// validly signed for the chain ID of the manifest and that the txs of every
+// sender have consecutive nonces, from the prepare txs on into its tx file.
+// It returns the problems found, an error means the store could not be read
+// at all.
+func (s *Store) Verify() ([]string, error) {
+	manifest, err := s.ReadManifest()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	paths, err := s.TxFiles()
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && len(manifest.Senders) != len(paths) {
+		return nil, fmt.Errorf("manifest lists %d senders, but there are %d tx files", len(manifest.Senders), len(paths))
+	}
+
+	var chainID *big.Int
+	// next is the nonce every account is expected to continue with
+	next := make(map[common.Address]uint64)
+	if manifest != nil {
+		chainID = manifest.ChainID
+		next[manifest.Faucet.Address] = manifest.Faucet.Nonce
+		for _, sender := range manifest.Senders {
+			next[sender.Address] = sender.Nonce
+		}
+	}
+
+	var problems []string
+	if path, ok := s.existingPrepareFile(); ok {
+		problems, err = verifyPrepareFile(path, chainID, manifest != nil, next)
+		if err != nil {
+			return problems, err
+		}
+	}
+
+	for index, path := range paths {
+		var expected *common.Address
+		if manifest != nil {
+			expected = &manifest.Senders[index].Address
+		}
+
+		fileProblems, err := verifyFile(path, chainID, expected, next)
+		if err != nil {
+			return problems, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+
+	return problems, nil
+}
+
+// verifyPrepareFile checks the prepare txs, which are sent by several
+// accounts. With a manifest, every account must be the faucet or a sender
+// and start at its nonce of the manifest. next is advanced past the prepare
+// txs of every account.
+func verifyPrepareFile(path string, chainID *big.Int, known bool, next map[common.Address]uint64) ([]string, error) {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		if len(problems) < maxProblemsPerFile {
+			problems = append(problems, fmt.Sprintf("%s: ", filepath.Base(path))+fmt.Sprintf(format, args...))
+		}
+	}
+
+	var count int
+	err := scanTxs(path, func(tx *types.Transaction) error {
+		defer func() { count++ }()
+
+		if chainID == nil {
+			chainID = tx.ChainId()
+		}
+		if tx.ChainId().Cmp(chainID) != 0 {
+			report("tx %d is signed for chain ID %v instead of %v", count, tx.ChainId(), chainID)
+		}
+
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			report("tx %d has an invalid signature: %v", count, err)
+			return nil
+		}
+
+		nonce, ok := next[sender]
+		if !ok && known {
+			report("tx %d is sent by %s, which is neither the faucet nor a sender", count, sender.Hex())
+		}
+		if ok && tx.Nonce() != nonce {
+			report("tx %d of %s has nonce %d instead of %d", count, sender.Hex(), tx.Nonce(), nonce)
+		}
+		next[sender] = tx.Nonce() + 1
+		return nil
+	})
+
+	return problems, err
+}
+
+// verifyFile checks the txs of one sender. Without a manifest, the chain ID
+// and sender of the first tx are expected for all of them. The first tx must
+// have the nonce next holds for the sender, if any.
+func verifyFile(path string, chainID *big.Int, expected *common.Address, next map[common.Address]uint64) ([]string, error) {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		if len(problems) < maxProblemsPerFile {
+			problems = append(problems, fmt.Sprintf("%s: ", filepath.Base(path))+fmt.Sprintf(format, args...))
+		}
+	}
+
+	var count int
+	var nonce uint64
+	err := scanTxs(path, func(tx *types.Transaction) error {
+		if chainID == nil {
+			chainID = tx.ChainId()
+		}
+		if tx.ChainId().Cmp(chainID) != 0 {
+			report("tx %d is signed for chain ID %v instead of %v", count, tx.ChainId(), chainID)
+		}
+
+		sender, err := util.SenderOf(tx)
+		if err != nil {
+			report("tx %d has an invalid signature: %v", count, err)
+		} else {
+			if expected == nil {
+				expected = &sender
+			}
+			if sender != *expected {
+				report("tx %d is sent by %s instead of %s", count, sender.Hex(), expected.Hex())
+			}
+		}
+
+		if count == 0 && expected != nil {
+			if first, ok := next[*expected]; ok && tx.Nonce() != first {
+				report("tx 0 has nonce %d, but the sender continues with nonce %d", tx.Nonce(), first)
+			}
+		} else if count > 0 && tx.Nonce() != nonce+1 {
+			report("tx %d has nonce %d after nonce %d", count, tx.Nonce(), nonce)
+		}
+		nonce = tx.Nonce()
+		count++
+		return nil
+	})
+
+	return problems, err
+}
+
+// Clear removes the files of the store, including its manifest and
+// checkpoint, and returns how many were removed.
+// Other files in the directory are left alone.
+func (s *Store) Clear() (int, error) {
+	paths, err := s.matchFiles(manifestFileName, checkpointFileName, "prepare.*", "transactions-*")
+	if err != nil {
+		return 0, err
+	}
+	return removeFiles(paths)
+}
+
+// removeTxFiles removes the tx files of the store and the checkpoint, which
+// refers to them, but keeps the manifest and the files being imported.
+func (s *Store) removeTxFiles() error {
+	paths, err := s.matchFiles(checkpointFileName, "prepare.*", "transactions-*")
+	if err != nil {
+		return err
+	}
+	var old []string
+	for _, path := range paths {
+		if !strings.HasSuffix(path, importSuffix) {
+			old = append(old, path)
+		}
+	}
+	_, err = removeFiles(old)
+	return err
+}
+
+func (s *Store) matchFiles(patterns ...string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(s.TxStoreDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func removeFiles(paths []string) (int, error) {
+	for i, path := range paths {
+		err := os.Remove(path)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(paths), nil
+}
+
+func (s *Store) existingPrepareFile() (string, bool) {
+	for _, ext := range []string{txFileExt, legacyTxFileExt} {
+		path := s.prepareFilePath(ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// scanTxs calls fn for every tx of the tx file at path.
+func scanTxs(path string, fn func(tx *types.Transaction) error) error {
+	source, err := openTxSource(path, 0)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	for {
+		tx, err := source.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(tx)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func txTypeName(txType uint8) string {
+	switch txType {
+	case types.LegacyTxType:
+		return "legacy"
+	case types.AccessListTxType:
+		return "access-list"
+	case types.DynamicFeeTxType:
+		return "dynamic-fee"
+	default:
+		return fmt.Sprintf("type-%d", txType)
+	}
+}
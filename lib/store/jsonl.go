@@ -0,0 +1,242 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PrepareSenderIndex is the sender index of prepare txs in JSONL records.
+const PrepareSenderIndex = -1
+
+// maxRecordSize bounds the length of a JSONL line, contract deployments are
+// the largest txs.
+const maxRecordSize = 4 * maxFrameSize
+
+// importSuffix marks the files written by Import until they replace the
+// files of the store.
+const importSuffix = ".tmp"
+
+// Record is one line of the JSONL export of a store. Prepare txs come first
+// with sender index -1, the workload txs of every sender follow in nonce
+// order. The manifest, if any, precedes them in a header line.
+type Record struct {
+	Sender int           `json:"sender"`
+	Nonce  uint64        `json:"nonce"`
+	Hash   common.Hash   `json:"hash"`
+	Raw    hexutil.Bytes `json:"raw"`
+}
+
+// header is the first line of the JSONL export of a store with a manifest.
+type header struct {
+	Manifest *Manifest `json:"manifest"`
+}
+
+// Export writes the manifest and all txs of the store to w as JSONL and
+// returns the count of txs.
+func (s *Store) Export(w io.Writer) (int, error) {
+	buf := bufio.NewWriterSize(w, chunkSize)
+	encoder := json.NewEncoder(buf)
+
+	manifest, err := s.ReadManifest()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	if manifest != nil {
+		err = encoder.Encode(header{Manifest: manifest})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var count int
+	export := func(sender int, path string) error {
+		return scanTxs(path, func(tx *types.Transaction) error {
+			raw, err := tx.MarshalBinary()
+			if err != nil {
+				return err
+			}
+
+			count++
+			return encoder.Encode(Record{Sender: sender, Nonce: tx.Nonce(), Hash: tx.Hash(), Raw: raw})
+		})
+	}
+
+	if path, ok := s.existingPrepareFile(); ok {
+		err := export(PrepareSenderIndex, path)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	paths, err := s.TxFiles()
+	if err != nil {
+		return count, err
+	}
+	for index, path := range paths {
+		err := export(index, path)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, buf.Flush()
+}
+
+// Import replaces the txs of the store with the JSONL records read from r and
+// returns their count. The records of every sender must be in nonce order.
+// The txs are written next to the files of the store, which are only replaced
+// once all records are valid. The manifest of the header replaces the one of
+// the store, without a header the manifest of the store is kept.
+func (s *Store) Import(r io.Reader) (int, error) {
+	writers := make(map[int]*TxWriter)
+	paths := make(map[int]string)
+	committed := false
+	defer func() {
+		for _, w := range writers {
+			w.Close()
+		}
+		if !committed {
+			for _, path := range paths {
+				os.Remove(path + importSuffix)
+			}
+		}
+	}()
+	writerOf := func(sender int) (*TxWriter, error) {
+		if w, ok := writers[sender]; ok {
+			return w, nil
+		}
+
+		path := s.prepareFilePath(txFileExt)
+		if sender != PrepareSenderIndex {
+			path = s.txsFilePath(sender)
+		}
+		w, err := s.createTxWriter(path + importSuffix)
+		if err != nil {
+			return nil, err
+		}
+		writers[sender] = w
+		paths[sender] = path
+		return w, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, chunkSize), maxRecordSize)
+	nonces := make(map[int]uint64)
+	var manifest *Manifest
+	var count int
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		if line == 1 {
+			var h header
+			err := json.Unmarshal(scanner.Bytes(), &h)
+			if err != nil {
+				return count, fmt.Errorf("line %d: %w", line, err)
+			}
+			if h.Manifest != nil {
+				manifest = h.Manifest
+				continue
+			}
+		}
+
+		var record Record
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Sender < PrepareSenderIndex {
+			return count, fmt.Errorf("line %d: sender index %d is not valid", line, record.Sender)
+		}
+
+		tx := new(types.Transaction)
+		err = tx.UnmarshalBinary(record.Raw)
+		if err != nil {
+			return count, fmt.Errorf("line %d: failed to decode raw tx: %w", line, err)
+		}
+		if tx.Hash() != record.Hash {
+			return count, fmt.Errorf("line %d: raw tx has hash %s instead of %s", line, tx.Hash().Hex(), record.Hash.Hex())
+		}
+		if tx.Nonce() != record.Nonce {
+			return count, fmt.Errorf("line %d: raw tx has nonce %d instead of %d", line, tx.Nonce(), record.Nonce)
+		}
+		if last, ok := nonces[record.Sender]; ok && record.Sender != PrepareSenderIndex && tx.Nonce() <= last {
+			return count, fmt.Errorf("line %d: nonce %d of sender %d follows nonce %d", line, tx.Nonce(), record.Sender, last)
+		}
+		nonces[record.Sender] = tx.Nonce()
+
+		w, err := writerOf(record.Sender)
+		if err != nil {
+			return count, err
+		}
+		err = w.Write(tx)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	senders := len(writers)
+	if _, ok := writers[PrepareSenderIndex]; ok {
+		senders--
+	}
+	for sender := 0; sender < senders; sender++ {
+		if _, ok := writers[sender]; !ok {
+			return count, fmt.Errorf("there are no txs of sender %d", sender)
+		}
+	}
+	if manifest != nil && len(manifest.Senders) != senders {
+		return count, fmt.Errorf("manifest lists %d senders, but there are txs of %d", len(manifest.Senders), senders)
+	}
+
+	for sender, w := range writers {
+		delete(writers, sender)
+		err := w.Close()
+		if err != nil {
+			return count, err
+		}
+	}
+
+	if manifest == nil {
+		var err error
+		manifest, err = s.ReadManifest()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return count, err
+		}
+	}
+
+	// the valid records replace the store from here on
+	err := s.removeTxFiles()
+	if err != nil {
+		return count, err
+	}
+	committed = true
+	for _, path := range paths {
+		err = os.Rename(path+importSuffix, path)
+		if err != nil {
+			return count, err
+		}
+	}
+	if manifest != nil {
+		manifest.Compression = s.Compression
+		err = s.WriteManifest(manifest)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	_, err = s.TxFiles()
+	return count, err
+}
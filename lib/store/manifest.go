@@ -0,0 +1,92 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const manifestFileName = "manifest.json"
+
+// Manifest records what the txs of a store were generated for, so that they
+// can be checked against the chain before they are loaded.
+type Manifest struct {
+	ChainID *big.Int `json:"chainId"`
+	// TxType is the workload, e.g. simple, erc20 or uniswap.
+	TxType     string `json:"txType"`
+	TxEnvelope string `json:"txEnvelope"`
+	// GasPrice is set for legacy and access-list txs, GasTipCap and GasFeeCap
+	// for dynamic-fee txs.
+	GasPrice  *big.Int `json:"gasPrice,omitempty"`
+	GasTipCap *big.Int `json:"gasTipCap,omitempty"`
+	GasFeeCap *big.Int `json:"gasFeeCap,omitempty"`
+	// Faucet sends the prepare txs, the senders the workload txs.
+	Faucet  ManifestAccount   `json:"faucet"`
+	Senders []ManifestAccount `json:"senders"`
+	// Compression of the tx files, detected when they are read.
+	Compression string `json:"compression,omitempty"`
+	// TxCount is the number of workload txs of every sender.
+	TxCount int `json:"txCount"`
+	// Deadline is the unix time the workload txs expire at, they revert
+	// afterwards. It is 0 if they never expire.
+	Deadline  int64     `json:"deadline,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ManifestAccount is an account with its nonce before the prepare txs. The
+// senders are throwaway accounts, their private keys are kept so that the txs
+// can be re-signed for another chain.
+type ManifestAccount struct {
+	Address    common.Address `json:"address"`
+	Nonce      uint64         `json:"nonce"`
+	PrivateKey hexutil.Bytes  `json:"privateKey,omitempty"`
+}
+
+// PriceCap returns the highest price per gas the txs may pay.
+func (m *Manifest) PriceCap() *big.Int {
+	if m.GasFeeCap != nil {
+		return m.GasFeeCap
+	}
+	return m.GasPrice
+}
+
+func (s *Store) WriteManifest(manifest *Manifest) error {
+	err := os.MkdirAll(s.TxStoreDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.manifestFilePath(), data, 0644)
+}
+
+// ReadManifest reads the manifest of the store. Stores written by older
+// versions have none, os.ErrNotExist is returned for them.
+func (s *Store) ReadManifest() (*Manifest, error) {
+	data, err := os.ReadFile(s.manifestFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.manifestFilePath(), err)
+	}
+
+	return &manifest, nil
+}
+
+func (s *Store) manifestFilePath() string {
+	return filepath.Join(s.TxStoreDir, manifestFileName)
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// retargetSuffix marks the files written by Retarget until they replace the
+// original ones.
+const retargetSuffix = ".retarget"
+
+// RetargetOptions describes the chain a store is re-signed for.
+type RetargetOptions struct {
+	ChainID *big.Int
+	// Faucet signs the prepare txs, it may differ from the original faucet.
+	Faucet      *ecdsa.PrivateKey
+	FaucetNonce uint64
+	// SenderNonces are the nonces the senders start at, in sender order.
+	SenderNonces []uint64
+}
+
+// retargetAccount is the key an original sender is re-signed with and the
+// shift of its nonces.
+type retargetAccount struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+	shift   int64
+}
+
+// Retarget re-signs every tx of the store for another chain, keeping the
+// shape of the workload. Nonces are shifted to the new starting nonces, and
+// the addresses of contracts created by the prepare txs are replaced with the
+// addresses they get when the prepare txs are replayed, in the recipient and
+// calldata of every tx. It returns the replaced addresses.
+func (s *Store) Retarget(options RetargetOptions) (map[common.Address]common.Address, error) {
+	manifest, err := s.ReadManifest()
+	if err != nil {
+		return nil, fmt.Errorf("a manifest is needed to retarget the store: %w", err)
+	}
+	if len(options.SenderNonces) != len(manifest.Senders) {
+		return nil, fmt.Errorf("got %d sender nonces for %d senders", len(options.SenderNonces), len(manifest.Senders))
+	}
+
+	faucet := crypto.PubkeyToAddress(options.Faucet.PublicKey)
+	accounts := map[common.Address]*retargetAccount{
+		manifest.Faucet.Address: {
+			key:     options.Faucet,
+			address: faucet,
+			shift:   int64(options.FaucetNonce) - int64(manifest.Faucet.Nonce),
+		},
+	}
+	for i, sender := range manifest.Senders {
+		if len(sender.PrivateKey) == 0 {
+			return nil, fmt.Errorf("the manifest has no private key of sender %s", sender.Address.Hex())
+		}
+		key, err := crypto.ToECDSA(sender.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key of sender %s: %w", sender.Address.Hex(), err)
+		}
+		accounts[sender.Address] = &retargetAccount{
+			key:     key,
+			address: sender.Address,
+			shift:   int64(options.SenderNonces[i]) - int64(sender.Nonce),
+		}
+	}
+
+	remap := make(map[common.Address]common.Address)
+	if faucet != manifest.Faucet.Address {
+		remap[manifest.Faucet.Address] = faucet
+	}
+
+	var renames [][2]string
+	retargetFile := func(src, dst string) error {
+		w, err := s.createTxWriter(dst + retargetSuffix)
+		if err != nil {
+			return err
+		}
+
+		err = scanTxs(src, func(tx *types.Transaction) error {
+			retargeted, err := retargetTx(tx, options.ChainID, accounts, remap)
+			if err != nil {
+				return fmt.Errorf("%s: tx %s: %w", filepath.Base(src), tx.Hash().Hex(), err)
+			}
+			return w.Write(retargeted)
+		})
+		if err != nil {
+			w.Close()
+			os.Remove(dst + retargetSuffix)
+			return err
+		}
+
+		renames = append(renames, [2]string{src, dst})
+		return w.Close()
+	}
+	cleanup := func() {
+		for _, rename := range renames {
+			os.Remove(rename[1] + retargetSuffix)
+		}
+	}
+
+	// the prepare txs go first, they create the contracts to remap
+	if path, ok := s.existingPrepareFile(); ok {
+		err = retargetFile(path, s.prepareFilePath(txFileExt))
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	paths, err := s.TxFiles()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	for index, path := range paths {
+		err = retargetFile(path, s.txsFilePath(index))
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	for _, rename := range renames {
+		err = os.Rename(rename[1]+retargetSuffix, rename[1])
+		if err != nil {
+			return nil, err
+		}
+		if rename[0] != rename[1] {
+			os.Remove(rename[0])
+		}
+	}
+
+	manifest.ChainID = options.ChainID
+	manifest.Faucet = ManifestAccount{Address: faucet, Nonce: options.FaucetNonce}
+	for i := range manifest.Senders {
+		manifest.Senders[i].Nonce = options.SenderNonces[i]
+	}
+	manifest.Compression = s.Compression
+
+	// the progress refers to the nonces of the old chain
+	err = os.Remove(filepath.Join(s.TxStoreDir, checkpointFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return remap, s.WriteManifest(manifest)
+}
+
+// retargetTx re-signs tx for chainID with the key of its sender. A contract
+// creation adds the address of the contract to remap.
+func retargetTx(tx *types.Transaction, chainID *big.Int, accounts map[common.Address]*retargetAccount, remap map[common.Address]common.Address) (*types.Transaction, error) {
+	sender, err := util.SenderOf(tx)
+	if err != nil {
+		return nil, err
+	}
+	account, ok := accounts[sender]
+	if !ok {
+		return nil, fmt.Errorf("sender %s is neither the faucet nor a sender of the manifest", sender.Hex())
+	}
+
+	shifted := int64(tx.Nonce()) + account.shift
+	if shifted < 0 {
+		return nil, fmt.Errorf("nonce %d of %s cannot be shifted by %d", tx.Nonce(), sender.Hex(), account.shift)
+	}
+	nonce := uint64(shifted)
+
+	to := tx.To()
+	if to == nil {
+		created := crypto.CreateAddress(account.address, nonce)
+		if original := crypto.CreateAddress(sender, tx.Nonce()); original != created {
+			remap[original] = created
+		}
+	} else if mapped, ok := remap[*to]; ok {
+		to = &mapped
+	}
+
+	data := tx.Data()
+	for original, mapped := range remap {
+		data = bytes.ReplaceAll(data, original.Bytes(), mapped.Bytes())
+	}
+
+	accessList := tx.AccessList()
+	for i, tuple := range accessList {
+		if mapped, ok := remap[tuple.Address]; ok {
+			accessList[i].Address = mapped
+		}
+	}
+
+	var txData types.TxData
+	switch tx.Type() {
+	case types.LegacyTxType:
+		txData = &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: tx.GasPrice(),
+			Gas:      tx.Gas(),
+			To:       to,
+			Value:    tx.Value(),
+			Data:     data,
+		}
+	case types.AccessListTxType:
+		txData = &types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   tx.GasPrice(),
+			Gas:        tx.Gas(),
+			To:         to,
+			Value:      tx.Value(),
+			Data:       data,
+			AccessList: accessList,
+		}
+	case types.DynamicFeeTxType:
+		txData = &types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasTipCap:  tx.GasTipCap(),
+			GasFeeCap:  tx.GasFeeCap(),
+			Gas:        tx.Gas(),
+			To:         to,
+			Value:      tx.Value(),
+			Data:       data,
+			AccessList: accessList,
+		}
+	default:
+		return nil, fmt.Errorf("tx type %d cannot be retargeted", tx.Type())
+	}
+
+	return types.SignNewTx(account.key, types.LatestSignerForChainID(chainID), txData)
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// IOStats counts the txs and bytes a store read or wrote. RawBytes is the
+// size of the frames before compression, DiskBytes the size of the files.
+type IOStats struct {
+	Txs       int
+	RawBytes  int64
+	DiskBytes int64
+	Duration  time.Duration
+}
+
+// Ratio returns the compression ratio, 1 for uncompressed files.
+func (s IOStats) Ratio() float64 {
+	if s.DiskBytes == 0 {
+		return 1
+	}
+	return float64(s.RawBytes) / float64(s.DiskBytes)
+}
+
+func (s IOStats) String() string {
+	str := fmt.Sprintf("%d txs, %.1f MB on disk, %.1f MB raw (ratio %.2f)",
+		s.Txs, float64(s.DiskBytes)/1e6, float64(s.RawBytes)/1e6, s.Ratio())
+	if s.Duration > 0 {
+		str += fmt.Sprintf(" in %v", s.Duration.Round(time.Millisecond))
+	}
+	return str
+}
+
+func (s *IOStats) add(other IOStats) {
+	s.Txs += other.Txs
+	s.RawBytes += other.RawBytes
+	s.DiskBytes += other.DiskBytes
+}
+
+func (s *Store) addWritten(stats IOStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.written.add(stats)
+}
+
+func (s *Store) addRead(stats IOStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.read.add(stats)
+}
+
+// Written returns what the store wrote so far.
+func (s *Store) Written() IOStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.written
+}
+
+// Read returns what the store read so far. The duration is the time the last
+// StreamTxs took to read all files.
+func (s *Store) Read() IOStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.read
+}
@@ -1,17 +1,49 @@
 package store
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/0glabs/evmchainbench/lib/util"
+)
+
+// Extensions of framed tx files and of tx files written by older versions,
+// which hold one RLP list of all txs.
+const (
+	txFileExt       = ".txs"
+	legacyTxFileExt = ".rlp"
 )
 
 type Store struct {
 	TxStoreDir     string
 	PrepareTxCache types.Transactions
+	// Compression is applied to the tx files written, see compress.go.
+	Compression string
+
+	mutex   sync.Mutex
+	written IOStats
+	read    IOStats
+}
+
+// TxStream carries the txs of every sender in nonce order, one channel per
+// sender, whether they are generated or read from disk. The channels are
+// closed once all txs are produced or producing them failed, in which case the
+// error is sent on Err before it is closed.
+type TxStream struct {
+	Txs []<-chan *types.Transaction
+	Err <-chan error
+	// Offsets are the offsets in the tx files of the first tx of every
+	// channel, nil unless the txs are read from framed tx files.
+	Offsets []int64
 }
 
 func NewStore(txStoreDir string) *Store {
@@ -25,13 +57,41 @@ func (s *Store) AddPrepareTx(tx *types.Transaction) {
 	s.PrepareTxCache = append(s.PrepareTxCache, tx)
 }
 
+// InsertPrepareTx caches tx before the first cached tx of its sender with a
+// higher nonce, so that the prepare txs of every sender stay in nonce order
+// when a nonce gap is filled after later txs were cached.
+func (s *Store) InsertPrepareTx(tx *types.Transaction) error {
+	sender, err := util.SenderOf(tx)
+	if err != nil {
+		return err
+	}
+
+	for i, cached := range s.PrepareTxCache {
+		if cached.Nonce() <= tx.Nonce() {
+			continue
+		}
+		from, err := util.SenderOf(cached)
+		if err != nil {
+			return err
+		}
+		if from == sender {
+			s.PrepareTxCache = append(s.PrepareTxCache[:i+1], s.PrepareTxCache[i:]...)
+			s.PrepareTxCache[i] = tx
+			return nil
+		}
+	}
+
+	s.AddPrepareTx(tx)
+	return nil
+}
+
 func (s *Store) PersistPrepareTxs() error {
-	return persistTxs(s.prepareFilePath(), s.PrepareTxCache)
+	return s.persistTxs(s.prepareFilePath(txFileExt), s.PrepareTxCache)
 }
 
 func (s *Store) PersistTxsMap(txsMap map[int]types.Transactions) error {
 	for index, txs := range txsMap {
-		err := persistTxs(s.txsFilePath(index), txs)
+		err := s.persistTxs(s.txsFilePath(index), txs)
 		if err != nil {
 			return err
 		}
@@ -40,77 +100,304 @@ func (s *Store) PersistTxsMap(txsMap map[int]types.Transactions) error {
 	return nil
 }
 
+// CreateTxsWriter creates the tx file of the sender with the given index, so
+// that its txs can be written while they are generated.
+func (s *Store) CreateTxsWriter(index int) (*TxWriter, error) {
+	return s.createTxWriter(s.txsFilePath(index))
+}
+
 func (s *Store) LoadPrepareTxs() (types.Transactions, error) {
-	return loadTxs(s.prepareFilePath())
+	path, ok := s.existingPrepareFile()
+	if !ok {
+		path = s.prepareFilePath(txFileExt)
+	}
+
+	return loadTxs(path)
 }
 
 func (s *Store) LoadTxsMap() (map[int]types.Transactions, error) {
 	txsMap := make(map[int]types.Transactions)
 
-	pattern := fmt.Sprintf("%s/transactions-*.rlp", s.TxStoreDir)
-	matches, err := filepath.Glob(pattern)
+	paths, err := s.TxFiles()
 	if err != nil {
 		return txsMap, err
 	}
 
-	if len(matches) == 0 {
-		return txsMap, fmt.Errorf("No transaction files are found")
-	} else {
-		for index, match := range matches {
-			txs, err := loadTxs(match)
+	for index, path := range paths {
+		txs, err := loadTxs(path)
+		if err != nil {
+			return txsMap, err
+		}
+
+		txsMap[index] = txs
+	}
+
+	return txsMap, nil
+}
+
+// TxFiles returns the tx files of all senders ordered by sender index. Legacy
+// files are only used if there are no framed ones.
+func (s *Store) TxFiles() ([]string, error) {
+	for _, ext := range []string{txFileExt, legacyTxFileExt} {
+		matches, err := filepath.Glob(filepath.Join(s.TxStoreDir, "transactions-*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		indexes := make(map[string]int, len(matches))
+		for _, match := range matches {
+			var index int
+			_, err := fmt.Sscanf(filepath.Base(match), "transactions-%d"+ext, &index)
 			if err != nil {
-				return txsMap, err
+				return nil, fmt.Errorf("unexpected tx file %s: %w", match, err)
+			}
+			indexes[match] = index
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			return indexes[matches[i]] < indexes[matches[j]]
+		})
+		for i, match := range matches {
+			if indexes[match] != i {
+				return nil, fmt.Errorf("tx file of sender %d is missing", i)
 			}
+		}
+
+		return matches, nil
+	}
+
+	return nil, fmt.Errorf("No transaction files are found")
+}
+
+// StreamTxs reads the tx files of all senders while their txs are consumed,
+// at most buffer txs per sender ahead. Reading of the i-th sender starts at
+// offsets[i] if offsets is not nil, so an interrupted load can be resumed.
+func (s *Store) StreamTxs(offsets []int64, buffer int) (*TxStream, error) {
+	paths, err := s.TxFiles()
+	if err != nil {
+		return nil, err
+	}
+	if offsets != nil && len(offsets) != len(paths) {
+		return nil, fmt.Errorf("got %d offsets for %d tx files", len(offsets), len(paths))
+	}
+
+	sources := make([]txSource, 0, len(paths))
+	starts := make([]int64, 0, len(paths))
+	for index, path := range paths {
+		var offset int64
+		if offsets != nil {
+			offset = offsets[index]
+		}
 
-			txsMap[index] = txs
+		source, err := openTxSource(path, offset)
+		if err != nil {
+			for _, source := range sources {
+				source.Close()
+			}
+			return nil, err
+		}
+		sources = append(sources, source)
+		if r, ok := source.(*TxReader); ok && starts != nil {
+			starts = append(starts, r.Offset())
+		} else {
+			starts = nil
 		}
 	}
 
-	return txsMap, nil
+	start := time.Now()
+	errCh := make(chan error, 1)
+	failed := make(chan struct{})
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			errCh <- err
+			close(failed)
+		})
+	}
+
+	streams := make([]<-chan *types.Transaction, len(sources))
+	var readers sync.WaitGroup
+	for index, source := range sources {
+		out := make(chan *types.Transaction, buffer)
+		streams[index] = out
+
+		readers.Add(1)
+		go func(source txSource) {
+			defer readers.Done()
+			defer close(out)
+			defer source.Close()
+
+			for {
+				tx, err := source.Next()
+				if err == io.EOF {
+					s.addRead(source.Stats())
+					return
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				select {
+				case out <- tx:
+				case <-failed:
+					return
+				}
+			}
+		}(source)
+	}
+
+	go func() {
+		readers.Wait()
+
+		s.mutex.Lock()
+		s.read.Duration = time.Since(start)
+		s.mutex.Unlock()
+
+		close(errCh)
+	}()
+
+	return &TxStream{Txs: streams, Err: errCh, Offsets: starts}, nil
 }
 
-func (s *Store) prepareFilePath() string {
-	return filepath.Join(s.TxStoreDir, "prepare.rlp")
+func (s *Store) prepareFilePath(ext string) string {
+	return filepath.Join(s.TxStoreDir, "prepare"+ext)
 }
 
 func (s *Store) txsFilePath(index int) string {
-	return filepath.Join(s.TxStoreDir, fmt.Sprintf("transactions-%d.rlp", index))
+	return filepath.Join(s.TxStoreDir, fmt.Sprintf("transactions-%d%s", index, txFileExt))
 }
 
-func persistTxs(path string, txs types.Transactions) error {
-	dir := filepath.Dir(path)
-	err := os.MkdirAll(dir, os.ModePerm)
+// createTxWriter creates a tx file compressed like the store, whose stats are
+// added to the written stats once it is closed.
+func (s *Store) createTxWriter(path string) (*TxWriter, error) {
+	w, err := CreateTxWriter(path, s.Compression)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	file, err := os.Create(path)
+	w.onClose = s.addWritten
+	return w, nil
+}
+
+func (s *Store) persistTxs(path string, txs types.Transactions) error {
+	w, err := s.createTxWriter(path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	err = rlp.Encode(file, txs)
-	if err != nil {
-		return err
+	for _, tx := range txs {
+		err = w.Write(tx)
+		if err != nil {
+			w.Close()
+			return err
+		}
 	}
 
-	return nil
+	return w.Close()
 }
 
 func loadTxs(path string) (types.Transactions, error) {
-	file, err := os.Open(path)
+	source, err := openTxSource(path, 0)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	defer source.Close()
 
 	var txs types.Transactions
+	for {
+		tx, err := source.Next()
+		if err == io.EOF {
+			return txs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, tx)
+	}
+}
+
+// txSource reads the txs of a tx file one by one.
+type txSource interface {
+	Next() (*types.Transaction, error)
+	Stats() IOStats
+	Close() error
+}
 
-	err = rlp.Decode(file, &txs)
+// ReadFirstTx reads the first tx of the tx file at path, framed or legacy.
+// It returns io.EOF if the file holds no txs.
+func ReadFirstTx(path string) (*types.Transaction, error) {
+	source, err := openTxSource(path, 0)
 	if err != nil {
 		return nil, err
 	}
+	defer source.Close()
+
+	return source.Next()
+}
+
+func openTxSource(path string, offset int64) (txSource, error) {
+	if filepath.Ext(path) != legacyTxFileExt {
+		return OpenTxReader(path, offset)
+	}
+	if offset != 0 {
+		return nil, fmt.Errorf("%s: legacy tx files cannot be read from an offset", path)
+	}
+
+	return openLegacyTxReader(path)
+}
+
+// legacyTxReader decodes the RLP list of a legacy tx file element by element
+// instead of all at once.
+type legacyTxReader struct {
+	file   *os.File
+	stream *rlp.Stream
+	count  int
+}
+
+func openLegacyTxReader(path string) (*legacyTxReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := rlp.NewStream(bufio.NewReaderSize(file, chunkSize), 0)
+	_, err = stream.List()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &legacyTxReader{file: file, stream: stream}, nil
+}
+
+func (r *legacyTxReader) Next() (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	err := r.stream.Decode(tx)
+	if err == rlp.EOL {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.file.Name(), err)
+	}
+
+	r.count++
+	return tx, nil
+}
+
+// Stats returns the txs read so far, legacy files are not compressed.
+func (r *legacyTxReader) Stats() IOStats {
+	stats := IOStats{Txs: r.count}
+	if info, err := r.file.Stat(); err == nil {
+		stats.RawBytes = info.Size()
+		stats.DiskBytes = info.Size()
+	}
+	return stats
+}
 
-	return txs, nil
+func (r *legacyTxReader) Close() error {
+	return r.file.Close()
 }
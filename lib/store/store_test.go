@@ -0,0 +1,32 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestInsertPrepareTx(t *testing.T) {
+	a := signedTxs(t, 4)
+	b := signedTxs(t, 1)
+
+	s := NewStore(t.TempDir())
+	for _, tx := range (types.Transactions{a[0], a[1], b[0], a[3]}) {
+		s.AddPrepareTx(tx)
+	}
+	// a filler of the gap at nonce 2, sent after nonce 3 was cached
+	err := s.InsertPrepareTx(a[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := types.Transactions{a[0], a[1], b[0], a[2], a[3]}
+	if len(s.PrepareTxCache) != len(want) {
+		t.Fatalf("cached %d txs, want %d", len(s.PrepareTxCache), len(want))
+	}
+	for i, tx := range want {
+		if s.PrepareTxCache[i].Hash() != tx.Hash() {
+			t.Fatalf("tx %d is %s, want %s", i, s.PrepareTxCache[i].Hash().Hex(), tx.Hash().Hex())
+		}
+	}
+}
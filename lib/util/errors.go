@@ -0,0 +1,139 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Errors returned by nodes over JSON-RPC lose their type, so they can only be
+// recognised by their message. Ethermint based chains report nonce problems
+// with Cosmos SDK wording, which is matched as well.
+var nonceErrorMessages = []string{
+	"nonce too low",
+	"nonce too high",
+	"invalid nonce",
+	"account sequence mismatch",
+	"incorrect account sequence",
+}
+
+var underpricedErrorMessages = []string{
+	"underpriced",
+	"less than block base fee",
+	"gas price too low",
+	"insufficient fee",
+	"minimum global fee",
+}
+
+// IsAlreadyKnownError reports whether err was caused by sending a tx the
+// node already has in its mempool.
+func IsAlreadyKnownError(err error) bool {
+	return errorContains(err, "already known", "already in mempool", "tx already exists in cache")
+}
+
+// IsNonceError reports whether err was caused by the node rejecting the nonce
+// of a transaction.
+func IsNonceError(err error) bool {
+	return errorContains(err, nonceErrorMessages...)
+}
+
+// IsUnderpricedError reports whether err was caused by the fees of a tx being
+// too low for the mempool, the base fee, or to replace a pending tx.
+func IsUnderpricedError(err error) bool {
+	return errorContains(err, underpricedErrorMessages...)
+}
+
+// ErrorClass sorts the errors of sending txs by how they can be dealt with.
+type ErrorClass string
+
+const (
+	AlreadyKnownClass      ErrorClass = "already-known"
+	NonceTooLowClass       ErrorClass = "nonce-too-low"
+	NonceGapClass          ErrorClass = "nonce-gap"
+	UnderpricedClass       ErrorClass = "underpriced"
+	TxpoolFullClass        ErrorClass = "txpool-full"
+	InsufficientFundsClass ErrorClass = "insufficient-funds"
+	TransportClass         ErrorClass = "transport"
+	OtherClass             ErrorClass = "other"
+)
+
+// ErrorClasses lists every class in the order they are reported in.
+var ErrorClasses = []ErrorClass{
+	AlreadyKnownClass,
+	NonceTooLowClass,
+	NonceGapClass,
+	UnderpricedClass,
+	TxpoolFullClass,
+	InsufficientFundsClass,
+	TransportClass,
+	OtherClass,
+}
+
+var nonceGapErrorMessages = []string{
+	"nonce too high",
+	"nonce gap",
+}
+
+var txpoolFullErrorMessages = []string{
+	"txpool is full",
+	"tx pool is full",
+	"mempool is full",
+	"transaction pool is full",
+}
+
+var insufficientFundsErrorMessages = []string{
+	"insufficient funds",
+	"insufficient balance",
+}
+
+// ClassifyError sorts an error of sending a tx into its class. Errors that
+// never reached a node, or came back as an HTTP error status, are transport
+// errors.
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case IsAlreadyKnownError(err):
+		return AlreadyKnownClass
+	case errorContains(err, nonceGapErrorMessages...):
+		return NonceGapClass
+	case IsNonceError(err):
+		return NonceTooLowClass
+	case IsUnderpricedError(err):
+		return UnderpricedClass
+	case errorContains(err, txpoolFullErrorMessages...):
+		return TxpoolFullClass
+	case errorContains(err, insufficientFundsErrorMessages...):
+		return InsufficientFundsClass
+	case isTransportError(err):
+		return TransportClass
+	default:
+		return OtherClass
+	}
+}
+
+func isTransportError(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	var httpErr rpc.HTTPError
+	return errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.As(err, &httpErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) ||
+		errorContains(err, "connection refused", "connection reset", "broken pipe")
+}
+
+func errorContains(err error, messages ...string) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class ErrorClass
+	}{
+		// geth
+		{"geth already known", errors.New("already known"), AlreadyKnownClass},
+		{"geth nonce too low", errors.New("nonce too low: address 0x71562b71999873DB5b286dF957af199Ec94617F7, tx: 5 state: 7"), NonceTooLowClass},
+		{"geth nonce too high", errors.New("nonce too high: address 0x71562b71999873DB5b286dF957af199Ec94617F7, tx: 9 state: 7"), NonceGapClass},
+		{"geth replacement underpriced", errors.New("replacement transaction underpriced"), UnderpricedClass},
+		{"geth underpriced", errors.New("transaction underpriced: tip needed 1000000000, tip permitted 1"), UnderpricedClass},
+		{"geth base fee", errors.New("max fee per gas less than block base fee: address 0x71562b71999873DB5b286dF957af199Ec94617F7, maxFeePerGas: 1 baseFee: 7"), UnderpricedClass},
+		{"geth txpool full", errors.New("txpool is full"), TxpoolFullClass},
+		{"geth insufficient funds", errors.New("insufficient funds for gas * price + value: address 0x71562b71999873DB5b286dF957af199Ec94617F7 have 0 want 21000"), InsufficientFundsClass},
+		{"geth intrinsic gas", errors.New("intrinsic gas too low: gas 20000, minimum needed 21000"), OtherClass},
+		{"geth reverted", errors.New("execution reverted"), OtherClass},
+
+		// Ethermint
+		{"ethermint already known", errors.New("tx already exists in cache"), AlreadyKnownClass},
+		{"ethermint invalid nonce", errors.New("invalid nonce; got 5, expected 7: invalid sequence"), NonceTooLowClass},
+		{"ethermint sequence mismatch", errors.New("account sequence mismatch, expected 7, got 5: incorrect account sequence"), NonceTooLowClass},
+		{"ethermint gas prices too low", errors.New("gas prices too low, got: 1aevmos required: 2aevmos. Please retry using a higher gas price or a higher fee: insufficient fee"), UnderpricedClass},
+		{"ethermint global fee", errors.New("provided fee < minimum global fee (1aevmos < 2aevmos). Please increase the gas price.: insufficient fee"), UnderpricedClass},
+		{"ethermint mempool full", errors.New("mempool is full: number of txs 5000 (max: 5000), total txs bytes 1048576 (max: 1073741824)"), TxpoolFullClass},
+		{"ethermint insufficient funds", errors.New("sender balance < tx cost (0 < 21000000000000): insufficient funds"), InsufficientFundsClass},
+
+		// transport
+		{"connection refused", &url.Error{Op: "Post", URL: "http://127.0.0.1:8545", Err: errors.New("dial tcp 127.0.0.1:8545: connect: connection refused")}, TransportClass},
+		{"http status", rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, TransportClass},
+		{"eof", fmt.Errorf("post: %w", io.EOF), TransportClass},
+		{"deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), TransportClass},
+		{"connection reset", errors.New("read tcp 127.0.0.1:51234->127.0.0.1:8545: read: connection reset by peer"), TransportClass},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class := ClassifyError(test.err)
+			if class != test.class {
+				t.Fatalf("%q is classified %s, want %s", test.err, class, test.class)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
@@ -37,3 +38,12 @@ func WaitForReceiptsOfTxs(client *ethclient.Client, txs types.Transactions, time
 
 	return nil
 }
+
+// SenderOf recovers the sender of tx, which may be signed for any chain or
+// none.
+func SenderOf(tx *types.Transaction) (common.Address, error) {
+	if !tx.Protected() {
+		return types.Sender(types.HomesteadSigner{}, tx)
+	}
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}